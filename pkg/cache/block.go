@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"encoding/binary"
+	"github.com/mjmar01/harmolytics/pkg/hmybebop"
+	"github.com/mjmar01/harmolytics/pkg/types"
+)
+
+var blockPrefix = []byte{0x05}
+
+// GetBlock returns a block by its number on a shard
+func (c *Cache) GetBlock(shardId uint, num uint64) (b *types.Block, ok bool) {
+	v, err := c.levelDB.Get(blockKey(shardId, num), nil)
+	if err != nil {
+		return nil, false
+	}
+	b, err = hmybebop.DecodeBlock(v)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// SetBlock stores a block under its number and shard
+func (c *Cache) SetBlock(b *types.Block) {
+	v, err := hmybebop.EncodeBlock(b)
+	if err != nil {
+		return
+	}
+	c.levelDB.Put(blockKey(b.ShardID, b.Number), v, nil)
+}
+
+func blockKey(shardId uint, num uint64) []byte {
+	key := make([]byte, 10)
+	copy(key, blockPrefix)
+	key[1] = byte(shardId)
+	binary.BigEndian.PutUint64(key[2:], num)
+	return key
+}
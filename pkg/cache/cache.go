@@ -18,6 +18,7 @@ func NewCache(opts *Opts) (newCache *Cache, err error) {
 	if opts.PreLoadTransactions {
 		newCache.loadTxMemory()
 		newCache.loadMMemory()
+		newCache.loadTkMemory()
 	}
 	return
 }
@@ -15,6 +15,12 @@ type Cache struct {
 	closeMutex sync.Mutex
 }
 
+// WalletCursor marks how far the transaction history of a wallet was synced
+type WalletCursor struct {
+	TxCount   uint64 // History entries seen so far
+	LastBlock uint64 // Highest block of a synced transaction
+}
+
 type Opts struct {
 	CacheDir            string
 	PreLoadTransactions bool
@@ -64,8 +64,8 @@ func (c *Cache) loadMMemory() {
 		src := iter.Value()
 		cp := make([]byte, len(src))
 		copy(cp, src)
+		wg.Add(1)
 		go func(in []byte) {
-			wg.Add(1)
 			mPtr, _ := hmybebop.DecodeMethod(in)
 			mMutex.Lock()
 			mMemory[mPtr.Signature] = mPtr
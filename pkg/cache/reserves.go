@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/mjmar01/harmolytics/pkg/hmybebop"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
+	"strings"
+)
+
+var reservesPrefix = []byte{0x06}
+
+// GetReserves returns the reserves of a liquidity pool at the end of a block in the order of the pool contract
+func (c *Cache) GetReserves(pool types.Address, blockNum uint64) (reserve0, reserve1 *big.Int, ok bool) {
+	v, err := c.levelDB.Get(reservesKey(pool, blockNum), nil)
+	if err != nil {
+		return nil, nil, false
+	}
+	reserve0, reserve1, err = hmybebop.DecodeReserves(v)
+	if err != nil {
+		return nil, nil, false
+	}
+	return reserve0, reserve1, true
+}
+
+// SetReserves stores the reserves of a liquidity pool at the end of a block
+func (c *Cache) SetReserves(pool types.Address, blockNum uint64, reserve0, reserve1 *big.Int) {
+	v, err := hmybebop.EncodeReserves(reserve0, reserve1)
+	if err != nil {
+		return
+	}
+	c.levelDB.Put(reservesKey(pool, blockNum), v, nil)
+}
+
+func reservesKey(pool types.Address, blockNum uint64) []byte {
+	addr, _ := hex.DecodeString(strings.TrimPrefix(pool.HexAddress, "0x"))
+	key := append(append([]byte{}, reservesPrefix...), addr...)
+	num := make([]byte, 8)
+	binary.BigEndian.PutUint64(num, blockNum)
+	return append(key, num...)
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"github.com/mjmar01/harmolytics/pkg/hmybebop"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"sync"
+)
+
+var stakingPrefix = []byte{0x04}
+var stakingMemory = map[string]*types.StakingTransaction{}
+var stakingMutex = sync.RWMutex{}
+
+func (c *Cache) GetStakingTransaction(hash string) (tx *types.StakingTransaction, ok bool) {
+	stakingMutex.RLock()
+	tx, ok = stakingMemory[hash]
+	stakingMutex.RUnlock()
+	if ok {
+		return
+	}
+	v, err := c.levelDB.Get(stakingKey(hash), nil)
+	if err != nil {
+		return nil, false
+	}
+	tx, err = hmybebop.DecodeStakingTransaction(v)
+	if err != nil {
+		return nil, false
+	}
+	stakingMutex.Lock()
+	stakingMemory[tx.TxHash] = tx
+	stakingMutex.Unlock()
+	return tx, true
+}
+
+func (c *Cache) SetStakingTransaction(tx *types.StakingTransaction) {
+	stakingMutex.Lock()
+	stakingMemory[tx.TxHash] = tx
+	stakingMutex.Unlock()
+	v, err := hmybebop.EncodeStakingTransaction(tx)
+	if err != nil {
+		return
+	}
+	c.levelDB.Put(stakingKey(tx.TxHash), v, nil)
+}
+
+func stakingKey(hash string) []byte {
+	return append(append([]byte{}, stakingPrefix...), []byte(hash)...)
+}
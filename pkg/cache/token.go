@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"github.com/mjmar01/harmolytics/pkg/hmybebop"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"sync"
+)
+
+var tkPrefix = []byte{0x07}
+var tkMemory = map[string]*types.Token{}
+var tkMutex = sync.RWMutex{}
+var loadedTk = false
+
+func (c *Cache) GetToken(addr types.Address) (tk *types.Token, ok bool) {
+	tkMutex.RLock()
+	tk, ok = tkMemory[addr.OneAddress]
+	tkMutex.RUnlock()
+	if ok {
+		return
+	}
+	v, err := c.levelDB.Get(tokenKey(addr), nil)
+	if err != nil {
+		return nil, false
+	}
+	tk, err = hmybebop.DecodeToken(v)
+	if err != nil {
+		return nil, false
+	}
+	tkMutex.Lock()
+	tkMemory[tk.Address.OneAddress] = tk
+	tkMutex.Unlock()
+	return tk, true
+}
+
+func (c *Cache) SetToken(tk *types.Token) {
+	tkMutex.Lock()
+	tkMemory[tk.Address.OneAddress] = tk
+	tkMutex.Unlock()
+	v, err := hmybebop.EncodeToken(tk)
+	if err != nil {
+		return
+	}
+	c.levelDB.Put(tokenKey(tk.Address), v, nil)
+}
+
+func (c *Cache) GetTokensByFilter(include func(tk *types.Token) bool) (tks []*types.Token) {
+	tkMutex.RLock()
+	loaded := loadedTk
+	tkMutex.RUnlock()
+	if !loaded {
+		c.loadTkMemory()
+	}
+	tkMutex.RLock()
+	defer tkMutex.RUnlock()
+	for _, tk := range tkMemory {
+		in := include(tk)
+		if in {
+			tks = append(tks, tk)
+		}
+	}
+	return
+}
+
+func (c *Cache) loadTkMemory() {
+	iter := c.levelDB.NewIterator(util.BytesPrefix(tkPrefix), nil)
+	for iter.Next() {
+		tkPtr, err := hmybebop.DecodeToken(iter.Value())
+		if err != nil {
+			continue
+		}
+		tkMutex.Lock()
+		tkMemory[tkPtr.Address.OneAddress] = tkPtr
+		tkMutex.Unlock()
+	}
+	iter.Release()
+	tkMutex.Lock()
+	loadedTk = true
+	tkMutex.Unlock()
+}
+
+func tokenKey(addr types.Address) []byte {
+	return append(append([]byte{}, tkPrefix...), []byte(addr.OneAddress)...)
+}
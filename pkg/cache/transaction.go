@@ -69,8 +69,8 @@ func (c *Cache) loadTxMemory() {
 		src := iter.Value()
 		cp := make([]byte, len(src))
 		copy(cp, src)
+		wg.Add(1)
 		go func(in []byte) {
-			wg.Add(1)
 			txPtr, _ := hmybebop.DecodeTransaction(in)
 			txMutex.Lock()
 			txMemoryByEthHash[txPtr.EthTxHash] = txPtr
@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"encoding/binary"
+	"github.com/mjmar01/harmolytics/pkg/types"
+)
+
+var walletPrefix = []byte{0x03}
+
+// GetWalletCursor returns the sync cursor of a wallet on a shard. ok is false if the wallet was never synced
+func (c *Cache) GetWalletCursor(shardId uint, addr types.Address) (cursor WalletCursor, ok bool) {
+	v, err := c.levelDB.Get(walletKey(shardId, addr), nil)
+	if err != nil || len(v) != 16 {
+		return WalletCursor{}, false
+	}
+	cursor.TxCount = binary.BigEndian.Uint64(v[:8])
+	cursor.LastBlock = binary.BigEndian.Uint64(v[8:])
+	return cursor, true
+}
+
+// SetWalletCursor stores the sync cursor of a wallet on a shard
+func (c *Cache) SetWalletCursor(shardId uint, addr types.Address, cursor WalletCursor) {
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint64(v[:8], cursor.TxCount)
+	binary.BigEndian.PutUint64(v[8:], cursor.LastBlock)
+	c.levelDB.Put(walletKey(shardId, addr), v, nil)
+}
+
+func walletKey(shardId uint, addr types.Address) []byte {
+	return append(append(append([]byte{}, walletPrefix...), byte(shardId)), []byte(addr.OneAddress)...)
+}
@@ -0,0 +1,72 @@
+package hmybebop
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"strings"
+)
+
+func EncodeBlock(b *types.Block) (data []byte, err error) {
+	hash, _ := hex.DecodeString(strings.TrimPrefix(b.Hash, "0x"))
+	bB := block{
+		number:    b.Number,
+		hash:      hash,
+		timeStamp: b.Timestamp,
+		miner: addr{
+			one: b.Leader.OneAddress,
+			hex: b.Leader.HexAddress,
+		},
+		gasUsed:         b.GasUsed,
+		txHashes:        encodeHashes(b.TxHashes),
+		stakingTxHashes: encodeHashes(b.StakingTxHashes),
+		shard:           byte(b.ShardID),
+	}
+	var buff bytes.Buffer
+	err = bB.EncodeBebop(&buff)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	data = buff.Bytes()
+	return
+}
+
+func DecodeBlock(data []byte) (b *types.Block, err error) {
+	bB := block{}
+	err = bB.DecodeBebop(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	b = &types.Block{
+		Number:    bB.number,
+		Hash:      "0x" + hex.EncodeToString(bB.hash),
+		ShardID:   uint(bB.shard),
+		Timestamp: bB.timeStamp,
+		Leader: types.Address{
+			OneAddress: bB.miner.one,
+			HexAddress: bB.miner.hex,
+		},
+		GasUsed:         bB.gasUsed,
+		TxHashes:        decodeHashes(bB.txHashes),
+		StakingTxHashes: decodeHashes(bB.stakingTxHashes),
+	}
+	return
+}
+
+// encodeHashes concatenates 32 byte hashes
+func encodeHashes(hashes []string) (data []byte) {
+	for _, hash := range hashes {
+		hashBytes, _ := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+		data = append(data, hashBytes...)
+	}
+	return
+}
+
+func decodeHashes(data []byte) (hashes []string) {
+	hashes = make([]string, len(data)/32)
+	for i := 32; i <= len(data); i += 32 {
+		hashes[(i/32)-1] = "0x" + hex.EncodeToString(data[i-32:i])
+	}
+	return
+}
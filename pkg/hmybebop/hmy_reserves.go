@@ -0,0 +1,30 @@
+package hmybebop
+
+import (
+	"bytes"
+	"github.com/go-errors/errors"
+	"math/big"
+)
+
+func EncodeReserves(reserve0, reserve1 *big.Int) (data []byte, err error) {
+	bR := reserves{
+		reserve0: reserve0.Bytes(),
+		reserve1: reserve1.Bytes(),
+	}
+	var buff bytes.Buffer
+	err = bR.EncodeBebop(&buff)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	data = buff.Bytes()
+	return
+}
+
+func DecodeReserves(data []byte) (reserve0, reserve1 *big.Int, err error) {
+	bR := reserves{}
+	err = bR.DecodeBebop(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, 0)
+	}
+	return new(big.Int).SetBytes(bR.reserve0), new(big.Int).SetBytes(bR.reserve1), nil
+}
@@ -0,0 +1,44 @@
+package hmybebop
+
+import (
+	"bytes"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/types"
+)
+
+func EncodeToken(tk *types.Token) (data []byte, err error) {
+	bTk := token{
+		address: addr{
+			one: tk.Address.OneAddress,
+			hex: tk.Address.HexAddress,
+		},
+		name:     tk.Name,
+		symbol:   tk.Symbol,
+		decimals: byte(tk.Decimals),
+	}
+	var buff bytes.Buffer
+	err = bTk.EncodeBebop(&buff)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	data = buff.Bytes()
+	return
+}
+
+func DecodeToken(data []byte) (tk *types.Token, err error) {
+	bTk := token{}
+	err = bTk.DecodeBebop(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	tk = &types.Token{
+		Address: types.Address{
+			OneAddress: bTk.address.one,
+			HexAddress: bTk.address.hex,
+		},
+		Name:     bTk.name,
+		Symbol:   bTk.symbol,
+		Decimals: int(bTk.decimals),
+	}
+	return
+}
@@ -13,24 +13,7 @@ func EncodeTransaction(tx *types.Transaction) (data []byte, err error) {
 	hash, _ := hex.DecodeString(strings.TrimPrefix(tx.TxHash, "0x"))
 	ethHash, _ := hex.DecodeString(strings.TrimPrefix(tx.EthTxHash, "0x"))
 	input, _ := hex.DecodeString(strings.TrimPrefix(tx.Input, "0x"))
-	logs := make([]log, len(tx.Logs))
-	for i, l := range tx.Logs {
-		var topics []byte
-		for _, topic := range l.Topics {
-			topicBytes, _ := hex.DecodeString(strings.TrimPrefix(topic, "0x"))
-			topics = append(topics, topicBytes...)
-		}
-		logData, _ := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
-		logs[i] = log{
-			index: uint16(l.LogIndex),
-			address: addr{
-				one: l.Address.OneAddress,
-				hex: l.Address.HexAddress,
-			},
-			topics: topics,
-			data:   logData,
-		}
-	}
+	logs := encodeLogs(tx.Logs)
 
 	bTx := transaction{
 		hash:    hash,
@@ -88,25 +71,7 @@ func DecodeTransaction(data []byte) (tx *types.Transaction, err error) {
 		HexAddress: bTx.receiver.hex,
 	}
 
-	logs := make([]types.TransactionLog, len(bTx.logs))
-	for i, l := range bTx.logs {
-		a := types.Address{
-			OneAddress: l.address.one,
-			HexAddress: l.address.hex,
-		}
-		logData := "0x" + hex.EncodeToString(l.data)
-		topics := make([]string, len(l.topics)/32)
-		for i := 32; i <= len(l.topics); i += 32 {
-			topics[(i/32)-1] = "0x" + hex.EncodeToString(l.topics[i-32:i])
-		}
-		logs[i] = types.TransactionLog{
-			TxHash:   hash,
-			LogIndex: int(l.index),
-			Address:  a,
-			Topics:   topics,
-			Data:     logData,
-		}
-	}
+	logs := decodeLogs(hash, uint64(bTx.blockNum), bTx.logs)
 
 	tx = &types.Transaction{
 		TxHash:    hash,
@@ -132,6 +97,83 @@ func DecodeTransaction(data []byte) (tx *types.Transaction, err error) {
 	return
 }
 
+func EncodeStakingTransaction(tx *types.StakingTransaction) (data []byte, err error) {
+	hash, _ := hex.DecodeString(strings.TrimPrefix(tx.TxHash, "0x"))
+	var amount []byte
+	if tx.Amount != nil {
+		amount = tx.Amount.Bytes()
+	}
+	var gasPrice []byte
+	if tx.GasPrice != nil {
+		gasPrice = tx.GasPrice.Bytes()
+	}
+
+	bTx := stakingTransaction{
+		hash:   hash,
+		txType: tx.Type,
+		sender: addr{
+			one: tx.Sender.OneAddress,
+			hex: tx.Sender.HexAddress,
+		},
+		delegator: addr{
+			one: tx.Delegator.OneAddress,
+			hex: tx.Delegator.HexAddress,
+		},
+		validator: addr{
+			one: tx.Validator.OneAddress,
+			hex: tx.Validator.HexAddress,
+		},
+		amount:    amount,
+		blockNum:  uint32(tx.BlockNum),
+		timeStamp: tx.Timestamp,
+		logs:      encodeLogs(tx.Logs),
+		status:    byte(tx.Status),
+		gasAmount: tx.GasAmount,
+		gasPrice:  gasPrice,
+	}
+	var buff bytes.Buffer
+	err = bTx.EncodeBebop(&buff)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	data = buff.Bytes()
+	return
+}
+
+func DecodeStakingTransaction(data []byte) (tx *types.StakingTransaction, err error) {
+	bTx := stakingTransaction{}
+	err = bTx.DecodeBebop(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	hash := "0x" + hex.EncodeToString(bTx.hash)
+	tx = &types.StakingTransaction{
+		TxHash: hash,
+		Type:   bTx.txType,
+		Sender: types.Address{
+			OneAddress: bTx.sender.one,
+			HexAddress: bTx.sender.hex,
+		},
+		Delegator: types.Address{
+			OneAddress: bTx.delegator.one,
+			HexAddress: bTx.delegator.hex,
+		},
+		Validator: types.Address{
+			OneAddress: bTx.validator.one,
+			HexAddress: bTx.validator.hex,
+		},
+		Amount:    new(big.Int).SetBytes(bTx.amount),
+		BlockNum:  uint64(bTx.blockNum),
+		Timestamp: bTx.timeStamp,
+		Logs:      decodeLogs(hash, uint64(bTx.blockNum), bTx.logs),
+		Status:    int(bTx.status),
+		GasAmount: bTx.gasAmount,
+		GasPrice:  new(big.Int).SetBytes(bTx.gasPrice),
+	}
+	return
+}
+
 func EncodeMethod(m *types.Method) (data []byte, err error) {
 	bM := method{
 		signature: m.Signature,
@@ -160,3 +202,49 @@ func DecodeMethod(data []byte) (m *types.Method, err error) {
 	}
 	return
 }
+
+func encodeLogs(ls []types.TransactionLog) (logs []log) {
+	logs = make([]log, len(ls))
+	for i, l := range ls {
+		var topics []byte
+		for _, topic := range l.Topics {
+			topicBytes, _ := hex.DecodeString(strings.TrimPrefix(topic, "0x"))
+			topics = append(topics, topicBytes...)
+		}
+		logData, _ := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
+		logs[i] = log{
+			index: uint16(l.LogIndex),
+			address: addr{
+				one: l.Address.OneAddress,
+				hex: l.Address.HexAddress,
+			},
+			topics: topics,
+			data:   logData,
+		}
+	}
+	return
+}
+
+func decodeLogs(hash string, blockNum uint64, ls []log) (logs []types.TransactionLog) {
+	logs = make([]types.TransactionLog, len(ls))
+	for i, l := range ls {
+		a := types.Address{
+			OneAddress: l.address.one,
+			HexAddress: l.address.hex,
+		}
+		logData := "0x" + hex.EncodeToString(l.data)
+		topics := make([]string, len(l.topics)/32)
+		for i := 32; i <= len(l.topics); i += 32 {
+			topics[(i/32)-1] = "0x" + hex.EncodeToString(l.topics[i-32:i])
+		}
+		logs[i] = types.TransactionLog{
+			TxHash:   hash,
+			LogIndex: int(l.index),
+			BlockNum: blockNum,
+			Address:  a,
+			Topics:   topics,
+			Data:     logData,
+		}
+	}
+	return
+}
@@ -641,3 +641,655 @@ func makelogFromBytes(buf []byte) (log, error) {
 	err := v.UnmarshalBebop(buf)
 	return v, err
 }
+
+var _ bebop.Record = &stakingTransaction{}
+
+type stakingTransaction struct {
+	hash      []byte
+	txType    string
+	sender    addr
+	delegator addr
+	validator addr
+	amount    []byte
+	blockNum  uint32
+	timeStamp uint64
+	logs      []log
+	status    byte
+	gasAmount uint32
+	gasPrice  []byte
+}
+
+func (bbp stakingTransaction) MarshalBebopTo(buf []byte) int {
+	at := 0
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.hash)))
+	at += 4
+	copy(buf[at:at+len(bbp.hash)], bbp.hash)
+	at += len(bbp.hash)
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.txType)))
+	copy(buf[at+4:at+4+len(bbp.txType)], []byte(bbp.txType))
+	at += 4 + len(bbp.txType)
+	(bbp.sender).MarshalBebopTo(buf[at:])
+	at += (bbp.sender).Size()
+	(bbp.delegator).MarshalBebopTo(buf[at:])
+	at += (bbp.delegator).Size()
+	(bbp.validator).MarshalBebopTo(buf[at:])
+	at += (bbp.validator).Size()
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.amount)))
+	at += 4
+	copy(buf[at:at+len(bbp.amount)], bbp.amount)
+	at += len(bbp.amount)
+	iohelp.WriteUint32Bytes(buf[at:], bbp.blockNum)
+	at += 4
+	iohelp.WriteUint64Bytes(buf[at:], bbp.timeStamp)
+	at += 8
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.logs)))
+	at += 4
+	for _, v1 := range bbp.logs {
+		(v1).MarshalBebopTo(buf[at:])
+		at += (v1).Size()
+	}
+	iohelp.WriteByteBytes(buf[at:], bbp.status)
+	at += 1
+	iohelp.WriteUint32Bytes(buf[at:], bbp.gasAmount)
+	at += 4
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.gasPrice)))
+	at += 4
+	copy(buf[at:at+len(bbp.gasPrice)], bbp.gasPrice)
+	at += len(bbp.gasPrice)
+	return at
+}
+
+func (bbp *stakingTransaction) UnmarshalBebop(buf []byte) (err error) {
+	at := 0
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.hash = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.hash)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.hash, buf[at:at+len(bbp.hash)])
+	at += len(bbp.hash)
+	bbp.txType, err = iohelp.ReadStringBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += 4 + len(bbp.txType)
+	bbp.sender, err = makeaddrFromBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += (bbp.sender).Size()
+	bbp.delegator, err = makeaddrFromBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += (bbp.delegator).Size()
+	bbp.validator, err = makeaddrFromBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += (bbp.validator).Size()
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.amount = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.amount)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.amount, buf[at:at+len(bbp.amount)])
+	at += len(bbp.amount)
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.blockNum = iohelp.ReadUint32Bytes(buf[at:])
+	at += 4
+	if len(buf[at:]) < 8 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.timeStamp = iohelp.ReadUint64Bytes(buf[at:])
+	at += 8
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.logs = make([]log, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	for i1 := range bbp.logs {
+		(bbp.logs)[i1], err = makelogFromBytes(buf[at:])
+		if err != nil {
+			return err
+		}
+		at += ((bbp.logs)[i1]).Size()
+	}
+	if len(buf[at:]) < 1 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.status = iohelp.ReadByteBytes(buf[at:])
+	at += 1
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.gasAmount = iohelp.ReadUint32Bytes(buf[at:])
+	at += 4
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.gasPrice = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.gasPrice)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.gasPrice, buf[at:at+len(bbp.gasPrice)])
+	at += len(bbp.gasPrice)
+	return nil
+}
+
+func (bbp stakingTransaction) EncodeBebop(iow io.Writer) (err error) {
+	w := iohelp.NewErrorWriter(iow)
+	iohelp.WriteUint32(w, uint32(len(bbp.hash)))
+	for _, elem := range bbp.hash {
+		iohelp.WriteByte(w, elem)
+	}
+	iohelp.WriteUint32(w, uint32(len(bbp.txType)))
+	w.Write([]byte(bbp.txType))
+	err = (bbp.sender).EncodeBebop(w)
+	if err != nil {
+		return err
+	}
+	err = (bbp.delegator).EncodeBebop(w)
+	if err != nil {
+		return err
+	}
+	err = (bbp.validator).EncodeBebop(w)
+	if err != nil {
+		return err
+	}
+	iohelp.WriteUint32(w, uint32(len(bbp.amount)))
+	for _, elem := range bbp.amount {
+		iohelp.WriteByte(w, elem)
+	}
+	iohelp.WriteUint32(w, bbp.blockNum)
+	iohelp.WriteUint64(w, bbp.timeStamp)
+	iohelp.WriteUint32(w, uint32(len(bbp.logs)))
+	for _, elem := range bbp.logs {
+		err = (elem).EncodeBebop(w)
+		if err != nil {
+			return err
+		}
+	}
+	iohelp.WriteByte(w, bbp.status)
+	iohelp.WriteUint32(w, bbp.gasAmount)
+	iohelp.WriteUint32(w, uint32(len(bbp.gasPrice)))
+	for _, elem := range bbp.gasPrice {
+		iohelp.WriteByte(w, elem)
+	}
+	return w.Err
+}
+
+func (bbp *stakingTransaction) DecodeBebop(ior io.Reader) (err error) {
+	r := iohelp.NewErrorReader(ior)
+	bbp.hash = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.hash {
+		(bbp.hash[i1]) = iohelp.ReadByte(r)
+	}
+	bbp.txType = iohelp.ReadString(r)
+	(bbp.sender), err = makeaddr(r)
+	if err != nil {
+		return err
+	}
+	(bbp.delegator), err = makeaddr(r)
+	if err != nil {
+		return err
+	}
+	(bbp.validator), err = makeaddr(r)
+	if err != nil {
+		return err
+	}
+	bbp.amount = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.amount {
+		(bbp.amount[i1]) = iohelp.ReadByte(r)
+	}
+	bbp.blockNum = iohelp.ReadUint32(r)
+	bbp.timeStamp = iohelp.ReadUint64(r)
+	bbp.logs = make([]log, iohelp.ReadUint32(r))
+	for i1 := range bbp.logs {
+		(bbp.logs[i1]), err = makelog(r)
+		if err != nil {
+			return err
+		}
+	}
+	bbp.status = iohelp.ReadByte(r)
+	bbp.gasAmount = iohelp.ReadUint32(r)
+	bbp.gasPrice = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.gasPrice {
+		(bbp.gasPrice[i1]) = iohelp.ReadByte(r)
+	}
+	return r.Err
+}
+
+func (bbp stakingTransaction) Size() int {
+	bodyLen := 0
+	bodyLen += 4
+	bodyLen += len(bbp.hash) * 1
+	bodyLen += 4 + len(bbp.txType)
+	bodyLen += (bbp.sender).Size()
+	bodyLen += (bbp.delegator).Size()
+	bodyLen += (bbp.validator).Size()
+	bodyLen += 4
+	bodyLen += len(bbp.amount) * 1
+	bodyLen += 4
+	bodyLen += 8
+	bodyLen += 4
+	for _, elem := range bbp.logs {
+		bodyLen += (elem).Size()
+	}
+	bodyLen += 1
+	bodyLen += 4
+	bodyLen += 4
+	bodyLen += len(bbp.gasPrice) * 1
+	return bodyLen
+}
+
+func (bbp stakingTransaction) MarshalBebop() []byte {
+	buf := make([]byte, bbp.Size())
+	bbp.MarshalBebopTo(buf)
+	return buf
+}
+
+func makestakingTransaction(r iohelp.ErrorReader) (stakingTransaction, error) {
+	v := stakingTransaction{}
+	err := v.DecodeBebop(r)
+	return v, err
+}
+
+func makestakingTransactionFromBytes(buf []byte) (stakingTransaction, error) {
+	v := stakingTransaction{}
+	err := v.UnmarshalBebop(buf)
+	return v, err
+}
+
+var _ bebop.Record = &block{}
+
+type block struct {
+	number          uint64
+	hash            []byte
+	timeStamp       uint64
+	miner           addr
+	gasUsed         uint64
+	txHashes        []byte
+	stakingTxHashes []byte
+	shard           byte
+}
+
+func (bbp block) MarshalBebopTo(buf []byte) int {
+	at := 0
+	iohelp.WriteUint64Bytes(buf[at:], bbp.number)
+	at += 8
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.hash)))
+	at += 4
+	copy(buf[at:at+len(bbp.hash)], bbp.hash)
+	at += len(bbp.hash)
+	iohelp.WriteUint64Bytes(buf[at:], bbp.timeStamp)
+	at += 8
+	(bbp.miner).MarshalBebopTo(buf[at:])
+	at += (bbp.miner).Size()
+	iohelp.WriteUint64Bytes(buf[at:], bbp.gasUsed)
+	at += 8
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.txHashes)))
+	at += 4
+	copy(buf[at:at+len(bbp.txHashes)], bbp.txHashes)
+	at += len(bbp.txHashes)
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.stakingTxHashes)))
+	at += 4
+	copy(buf[at:at+len(bbp.stakingTxHashes)], bbp.stakingTxHashes)
+	at += len(bbp.stakingTxHashes)
+	iohelp.WriteByteBytes(buf[at:], bbp.shard)
+	at += 1
+	return at
+}
+
+func (bbp *block) UnmarshalBebop(buf []byte) (err error) {
+	at := 0
+	if len(buf[at:]) < 8 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.number = iohelp.ReadUint64Bytes(buf[at:])
+	at += 8
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.hash = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.hash)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.hash, buf[at:at+len(bbp.hash)])
+	at += len(bbp.hash)
+	if len(buf[at:]) < 8 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.timeStamp = iohelp.ReadUint64Bytes(buf[at:])
+	at += 8
+	bbp.miner, err = makeaddrFromBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += (bbp.miner).Size()
+	if len(buf[at:]) < 8 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.gasUsed = iohelp.ReadUint64Bytes(buf[at:])
+	at += 8
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.txHashes = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.txHashes)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.txHashes, buf[at:at+len(bbp.txHashes)])
+	at += len(bbp.txHashes)
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.stakingTxHashes = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.stakingTxHashes)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.stakingTxHashes, buf[at:at+len(bbp.stakingTxHashes)])
+	at += len(bbp.stakingTxHashes)
+	if len(buf[at:]) < 1 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.shard = iohelp.ReadByteBytes(buf[at:])
+	at += 1
+	return nil
+}
+
+func (bbp block) EncodeBebop(iow io.Writer) (err error) {
+	w := iohelp.NewErrorWriter(iow)
+	iohelp.WriteUint64(w, bbp.number)
+	iohelp.WriteUint32(w, uint32(len(bbp.hash)))
+	for _, elem := range bbp.hash {
+		iohelp.WriteByte(w, elem)
+	}
+	iohelp.WriteUint64(w, bbp.timeStamp)
+	err = (bbp.miner).EncodeBebop(w)
+	if err != nil {
+		return err
+	}
+	iohelp.WriteUint64(w, bbp.gasUsed)
+	iohelp.WriteUint32(w, uint32(len(bbp.txHashes)))
+	for _, elem := range bbp.txHashes {
+		iohelp.WriteByte(w, elem)
+	}
+	iohelp.WriteUint32(w, uint32(len(bbp.stakingTxHashes)))
+	for _, elem := range bbp.stakingTxHashes {
+		iohelp.WriteByte(w, elem)
+	}
+	iohelp.WriteByte(w, bbp.shard)
+	return w.Err
+}
+
+func (bbp *block) DecodeBebop(ior io.Reader) (err error) {
+	r := iohelp.NewErrorReader(ior)
+	bbp.number = iohelp.ReadUint64(r)
+	bbp.hash = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.hash {
+		(bbp.hash[i1]) = iohelp.ReadByte(r)
+	}
+	bbp.timeStamp = iohelp.ReadUint64(r)
+	(bbp.miner), err = makeaddr(r)
+	if err != nil {
+		return err
+	}
+	bbp.gasUsed = iohelp.ReadUint64(r)
+	bbp.txHashes = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.txHashes {
+		(bbp.txHashes[i1]) = iohelp.ReadByte(r)
+	}
+	bbp.stakingTxHashes = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.stakingTxHashes {
+		(bbp.stakingTxHashes[i1]) = iohelp.ReadByte(r)
+	}
+	bbp.shard = iohelp.ReadByte(r)
+	return r.Err
+}
+
+func (bbp block) Size() int {
+	bodyLen := 0
+	bodyLen += 8
+	bodyLen += 4
+	bodyLen += len(bbp.hash) * 1
+	bodyLen += 8
+	bodyLen += (bbp.miner).Size()
+	bodyLen += 8
+	bodyLen += 4
+	bodyLen += len(bbp.txHashes) * 1
+	bodyLen += 4
+	bodyLen += len(bbp.stakingTxHashes) * 1
+	bodyLen += 1
+	return bodyLen
+}
+
+func (bbp block) MarshalBebop() []byte {
+	buf := make([]byte, bbp.Size())
+	bbp.MarshalBebopTo(buf)
+	return buf
+}
+
+func makeblock(r iohelp.ErrorReader) (block, error) {
+	v := block{}
+	err := v.DecodeBebop(r)
+	return v, err
+}
+
+func makeblockFromBytes(buf []byte) (block, error) {
+	v := block{}
+	err := v.UnmarshalBebop(buf)
+	return v, err
+}
+
+var _ bebop.Record = &reserves{}
+
+type reserves struct {
+	reserve0 []byte
+	reserve1 []byte
+}
+
+func (bbp reserves) MarshalBebopTo(buf []byte) int {
+	at := 0
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.reserve0)))
+	at += 4
+	copy(buf[at:at+len(bbp.reserve0)], bbp.reserve0)
+	at += len(bbp.reserve0)
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.reserve1)))
+	at += 4
+	copy(buf[at:at+len(bbp.reserve1)], bbp.reserve1)
+	at += len(bbp.reserve1)
+	return at
+}
+
+func (bbp *reserves) UnmarshalBebop(buf []byte) (err error) {
+	at := 0
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.reserve0 = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.reserve0)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.reserve0, buf[at:at+len(bbp.reserve0)])
+	at += len(bbp.reserve0)
+	if len(buf[at:]) < 4 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.reserve1 = make([]byte, iohelp.ReadUint32Bytes(buf[at:]))
+	at += 4
+	if len(buf[at:]) < len(bbp.reserve1)*1 {
+		return io.ErrUnexpectedEOF
+	}
+	copy(bbp.reserve1, buf[at:at+len(bbp.reserve1)])
+	at += len(bbp.reserve1)
+	return nil
+}
+
+func (bbp reserves) EncodeBebop(iow io.Writer) (err error) {
+	w := iohelp.NewErrorWriter(iow)
+	iohelp.WriteUint32(w, uint32(len(bbp.reserve0)))
+	for _, elem := range bbp.reserve0 {
+		iohelp.WriteByte(w, elem)
+	}
+	iohelp.WriteUint32(w, uint32(len(bbp.reserve1)))
+	for _, elem := range bbp.reserve1 {
+		iohelp.WriteByte(w, elem)
+	}
+	return w.Err
+}
+
+func (bbp *reserves) DecodeBebop(ior io.Reader) (err error) {
+	r := iohelp.NewErrorReader(ior)
+	bbp.reserve0 = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.reserve0 {
+		(bbp.reserve0[i1]) = iohelp.ReadByte(r)
+	}
+	bbp.reserve1 = make([]byte, iohelp.ReadUint32(r))
+	for i1 := range bbp.reserve1 {
+		(bbp.reserve1[i1]) = iohelp.ReadByte(r)
+	}
+	return r.Err
+}
+
+func (bbp reserves) Size() int {
+	bodyLen := 0
+	bodyLen += 4
+	bodyLen += len(bbp.reserve0) * 1
+	bodyLen += 4
+	bodyLen += len(bbp.reserve1) * 1
+	return bodyLen
+}
+
+func (bbp reserves) MarshalBebop() []byte {
+	buf := make([]byte, bbp.Size())
+	bbp.MarshalBebopTo(buf)
+	return buf
+}
+
+func makereserves(r iohelp.ErrorReader) (reserves, error) {
+	v := reserves{}
+	err := v.DecodeBebop(r)
+	return v, err
+}
+
+func makereservesFromBytes(buf []byte) (reserves, error) {
+	v := reserves{}
+	err := v.UnmarshalBebop(buf)
+	return v, err
+}
+
+var _ bebop.Record = &token{}
+
+type token struct {
+	address  addr
+	name     string
+	symbol   string
+	decimals byte
+}
+
+func (bbp token) MarshalBebopTo(buf []byte) int {
+	at := 0
+	(bbp.address).MarshalBebopTo(buf[at:])
+	at += (bbp.address).Size()
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.name)))
+	copy(buf[at+4:at+4+len(bbp.name)], []byte(bbp.name))
+	at += 4 + len(bbp.name)
+	iohelp.WriteUint32Bytes(buf[at:], uint32(len(bbp.symbol)))
+	copy(buf[at+4:at+4+len(bbp.symbol)], []byte(bbp.symbol))
+	at += 4 + len(bbp.symbol)
+	iohelp.WriteByteBytes(buf[at:], bbp.decimals)
+	at += 1
+	return at
+}
+
+func (bbp *token) UnmarshalBebop(buf []byte) (err error) {
+	at := 0
+	bbp.address, err = makeaddrFromBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += (bbp.address).Size()
+	bbp.name, err = iohelp.ReadStringBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += 4 + len(bbp.name)
+	bbp.symbol, err = iohelp.ReadStringBytes(buf[at:])
+	if err != nil {
+		return err
+	}
+	at += 4 + len(bbp.symbol)
+	if len(buf[at:]) < 1 {
+		return io.ErrUnexpectedEOF
+	}
+	bbp.decimals = iohelp.ReadByteBytes(buf[at:])
+	at += 1
+	return nil
+}
+
+func (bbp token) EncodeBebop(iow io.Writer) (err error) {
+	w := iohelp.NewErrorWriter(iow)
+	err = (bbp.address).EncodeBebop(w)
+	if err != nil {
+		return err
+	}
+	iohelp.WriteUint32(w, uint32(len(bbp.name)))
+	w.Write([]byte(bbp.name))
+	iohelp.WriteUint32(w, uint32(len(bbp.symbol)))
+	w.Write([]byte(bbp.symbol))
+	iohelp.WriteByte(w, bbp.decimals)
+	return w.Err
+}
+
+func (bbp *token) DecodeBebop(ior io.Reader) (err error) {
+	r := iohelp.NewErrorReader(ior)
+	(bbp.address), err = makeaddr(r)
+	if err != nil {
+		return err
+	}
+	bbp.name = iohelp.ReadString(r)
+	bbp.symbol = iohelp.ReadString(r)
+	bbp.decimals = iohelp.ReadByte(r)
+	return r.Err
+}
+
+func (bbp token) Size() int {
+	bodyLen := 0
+	bodyLen += (bbp.address).Size()
+	bodyLen += 4 + len(bbp.name)
+	bodyLen += 4 + len(bbp.symbol)
+	bodyLen += 1
+	return bodyLen
+}
+
+func (bbp token) MarshalBebop() []byte {
+	buf := make([]byte, bbp.Size())
+	bbp.MarshalBebopTo(buf)
+	return buf
+}
+
+func maketoken(r iohelp.ErrorReader) (token, error) {
+	v := token{}
+	err := v.DecodeBebop(r)
+	return v, err
+}
+
+func maketokenFromBytes(buf []byte) (token, error) {
+	v := token{}
+	err := v.UnmarshalBebop(buf)
+	return v, err
+}
@@ -0,0 +1,241 @@
+package hmydecode
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// AbiRegistry holds contract ABIs by contract address and the events of all ABIs by topic0
+type AbiRegistry struct {
+	abis   map[string]*abi.ABI
+	events map[string][]abi.Event
+	mutex  sync.RWMutex
+}
+
+var defaultRegistry = NewAbiRegistry()
+
+// NewAbiRegistry creates a registry which knows the HRC-20, HRC-721, Wrapped ONE and UniswapV2 events
+func NewAbiRegistry() *AbiRegistry {
+	r := &AbiRegistry{abis: map[string]*abi.ABI{}, events: map[string][]abi.Event{}}
+	for _, standard := range []string{standardEventsAbi, nftEventsAbi} {
+		standardAbi, err := abi.JSON(strings.NewReader(standard))
+		if err != nil {
+			panic(err)
+		}
+		r.addEvents(&standardAbi)
+	}
+	return r
+}
+
+// RegisterAbi reads a standard ABI JSON into the default registry, see AbiRegistry.Register
+func RegisterAbi(addr types.Address, r io.Reader) (err error) {
+	return defaultRegistry.Register(addr, r)
+}
+
+// RegisterAbiFile reads a standard ABI JSON file into the default registry, see AbiRegistry.Register
+func RegisterAbiFile(addr types.Address, path string) (err error) {
+	return defaultRegistry.RegisterFile(addr, path)
+}
+
+// DecodeCall decodes the input of tx with the default registry, see AbiRegistry.DecodeCall
+func DecodeCall(tx types.Transaction) (c types.Call, ok bool, err error) {
+	return defaultRegistry.DecodeCall(tx)
+}
+
+// DecodeEvent decodes log with the default registry, see AbiRegistry.DecodeEvent
+func DecodeEvent(log types.TransactionLog) (e types.Event, ok bool, err error) {
+	return defaultRegistry.DecodeEvent(log)
+}
+
+// Register reads a standard ABI JSON as the ABI of the contract at addr.
+// An ABI registered for the empty Address is used for all contracts without their own ABI, e.g. for HRC-20 methods
+func (r *AbiRegistry) Register(addr types.Address, abiJson io.Reader) (err error) {
+	contractAbi, err := abi.JSON(abiJson)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	r.mutex.Lock()
+	r.abis[addr.OneAddress] = &contractAbi
+	r.mutex.Unlock()
+	r.addEvents(&contractAbi)
+	return
+}
+
+// RegisterFile reads a standard ABI JSON file, see Register
+func (r *AbiRegistry) RegisterFile(addr types.Address, path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	defer f.Close()
+	return r.Register(addr, f)
+}
+
+// DecodeCall decodes the input of tx with the ABI of its receiver.
+// ok is false if no ABI is known for the receiver or the ABI doesn't contain the called method
+func (r *AbiRegistry) DecodeCall(tx types.Transaction) (c types.Call, ok bool, err error) {
+	input, err := hex.DecodeString(strings.TrimPrefix(tx.Input, "0x"))
+	if err != nil {
+		return types.Call{}, false, errors.Wrap(err, 0)
+	}
+	if len(input) < 4 {
+		return types.Call{}, false, nil
+	}
+	// Look up the method, contracts without own ABI fall back to the generic one
+	r.mutex.RLock()
+	var method *abi.Method
+	for _, key := range []string{tx.Receiver.OneAddress, ""} {
+		if contractAbi, known := r.abis[key]; known {
+			if method, err = contractAbi.MethodById(input[:4]); err == nil {
+				break
+			}
+		}
+	}
+	r.mutex.RUnlock()
+	if method == nil {
+		return types.Call{}, false, nil
+	}
+	values, err := hmysolidityio.Decode(argTypes(method.Inputs), tx.Input)
+	if err != nil {
+		return types.Call{}, false, err
+	}
+	c = types.Call{
+		TxHash:   tx.TxHash,
+		Contract: tx.Receiver,
+		Method: types.Method{
+			Signature:  hex.EncodeToString(input[:4]),
+			Name:       method.RawName,
+			Parameters: make([]string, len(method.Inputs)),
+		},
+		Arguments: make([]types.Argument, len(method.Inputs)),
+	}
+	for i, arg := range method.Inputs {
+		c.Method.Parameters[i] = arg.Type.String()
+		c.Arguments[i] = types.Argument{
+			Name:  arg.Name,
+			Type:  arg.Type.String(),
+			Value: argValue(arg.Type, values[i]),
+		}
+	}
+	return c, true, nil
+}
+
+// DecodeEvent decodes log with the event registered for its topic0.
+// Events sharing a topic0 are told apart by their number of indexed arguments, e.g. HRC-20 and HRC-721 transfers.
+// ok is false if no matching event is known
+func (r *AbiRegistry) DecodeEvent(log types.TransactionLog) (e types.Event, ok bool, err error) {
+	if len(log.Topics) == 0 {
+		return types.Event{}, false, nil
+	}
+	var event *abi.Event
+	r.mutex.RLock()
+	for i, candidate := range r.events[strings.ToLower(log.Topics[0])] {
+		indexed := len(candidate.Inputs) - len(candidate.Inputs.NonIndexed())
+		if indexed == len(log.Topics)-1 {
+			event = &r.events[strings.ToLower(log.Topics[0])][i]
+			break
+		}
+	}
+	r.mutex.RUnlock()
+	if event == nil {
+		return types.Event{}, false, nil
+	}
+	// Non-indexed arguments are in the data
+	values, err := hmysolidityio.Decode(argTypes(event.Inputs.NonIndexed()), log.Data)
+	if err != nil {
+		return types.Event{}, false, err
+	}
+	e = types.Event{
+		TxHash:    log.TxHash,
+		LogIndex:  log.LogIndex,
+		Address:   log.Address,
+		Name:      event.RawName,
+		Arguments: make([]types.Argument, len(event.Inputs)),
+	}
+	topic, value := 1, 0
+	for i, arg := range event.Inputs {
+		e.Arguments[i] = types.Argument{Name: arg.Name, Type: arg.Type.String()}
+		if !arg.Indexed {
+			e.Arguments[i].Value = argValue(arg.Type, values[value])
+			value++
+			continue
+		}
+		// Indexed arguments are one topic each
+		switch arg.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			// Dynamic values are hashed
+			e.Arguments[i].Value, err = hex.DecodeString(strings.TrimPrefix(log.Topics[topic], "0x"))
+			if err != nil {
+				return types.Event{}, false, errors.Wrap(err, 0)
+			}
+		default:
+			topicValues, err := hmysolidityio.Decode(arg.Type.String(), log.Topics[topic])
+			if err != nil {
+				return types.Event{}, false, err
+			}
+			e.Arguments[i].Value = argValue(arg.Type, topicValues[0])
+		}
+		topic++
+	}
+	return e, true, nil
+}
+
+// addEvents registers all non-anonymous events of contractAbi by topic0. Known events are not added again
+func (r *AbiRegistry) addEvents(contractAbi *abi.ABI) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+events:
+	for _, event := range contractAbi.Events {
+		if event.Anonymous {
+			continue
+		}
+		topic0 := strings.ToLower(event.ID.Hex())
+		for _, known := range r.events[topic0] {
+			if known.String() == event.String() {
+				continue events
+			}
+		}
+		r.events[topic0] = append(r.events[topic0], event)
+	}
+}
+
+// argTypes returns the comma separated types of args
+func argTypes(args abi.Arguments) string {
+	ts := make([]string, len(args))
+	for i, arg := range args {
+		ts[i] = arg.Type.String()
+	}
+	return strings.Join(ts, ",")
+}
+
+// argValue names the tuple components of a value decoded by hmysolidityio as described by types.Argument
+func argValue(t abi.Type, v interface{}) interface{} {
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		arr := v.([]interface{})
+		for i := range arr {
+			arr[i] = argValue(*t.Elem, arr[i])
+		}
+		return arr
+	case abi.TupleTy:
+		values := v.([]interface{})
+		components := make([]types.Argument, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			components[i] = types.Argument{
+				Name:  t.TupleRawNames[i],
+				Type:  elem.String(),
+				Value: argValue(*elem, values[i]),
+			}
+		}
+		return components
+	default:
+		return v
+	}
+}
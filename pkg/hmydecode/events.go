@@ -0,0 +1,38 @@
+package hmydecode
+
+// Events known to every AbiRegistry
+const (
+	standardEventsAbi = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Approval","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Deposit","anonymous":false,"inputs":[
+		{"name":"dst","type":"address","indexed":true},{"name":"wad","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Withdrawal","anonymous":false,"inputs":[
+		{"name":"src","type":"address","indexed":true},{"name":"wad","type":"uint256","indexed":false}]},
+	{"type":"event","name":"PairCreated","anonymous":false,"inputs":[
+		{"name":"token0","type":"address","indexed":true},{"name":"token1","type":"address","indexed":true},
+		{"name":"pair","type":"address","indexed":false},{"name":"","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Mint","anonymous":false,"inputs":[
+		{"name":"sender","type":"address","indexed":true},{"name":"amount0","type":"uint256","indexed":false},{"name":"amount1","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Burn","anonymous":false,"inputs":[
+		{"name":"sender","type":"address","indexed":true},{"name":"amount0","type":"uint256","indexed":false},
+		{"name":"amount1","type":"uint256","indexed":false},{"name":"to","type":"address","indexed":true}]},
+	{"type":"event","name":"Swap","anonymous":false,"inputs":[
+		{"name":"sender","type":"address","indexed":true},{"name":"amount0In","type":"uint256","indexed":false},
+		{"name":"amount1In","type":"uint256","indexed":false},{"name":"amount0Out","type":"uint256","indexed":false},
+		{"name":"amount1Out","type":"uint256","indexed":false},{"name":"to","type":"address","indexed":true}]},
+	{"type":"event","name":"Sync","anonymous":false,"inputs":[
+		{"name":"reserve0","type":"uint112","indexed":false},{"name":"reserve1","type":"uint112","indexed":false}]}
+]`
+	// HRC-721 events share their topic0 with HRC-20 events but index every argument
+	nftEventsAbi = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+	{"type":"event","name":"Approval","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},{"name":"approved","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+	{"type":"event","name":"ApprovalForAll","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},{"name":"operator","type":"address","indexed":true},{"name":"approved","type":"bool","indexed":false}]}
+]`
+)
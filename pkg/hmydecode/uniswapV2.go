@@ -13,6 +13,7 @@ const (
 	wone = "one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua"
 
 	swapEvent                                             = "0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822"
+	swapEventData                                         = "(uint256,uint256,uint256,uint256)"
 	swapETHForExactTokens                                 = "fb3bdb41"
 	swapExactETHForTokens                                 = "7ff36ab5"
 	swapExactETHForTokensSupportingFeeOnTransferTokens    = "b6f9de95"
@@ -65,34 +66,19 @@ func DecodeSwap(tx types.Transaction) (s types.Swap, ok bool, err error) {
 	})
 	for _, txLog := range tx.Logs {
 		if txLog.Topics[0] == swapEvent {
+			// amount0In, amount1In, amount0Out, amount1Out
+			amounts, err := hmysolidityio.Decode(swapEventData, txLog.Data)
+			if err != nil {
+				return types.Swap{}, false, err
+			}
 			// If it's the first swap of the path read input amount
 			if pathLeft == len(path)-1 {
-				inputAmount := big.NewInt(0)
-				aIn0, err := hmysolidityio.DecodeInt(txLog.Data, 0)
-				if err != nil {
-					return types.Swap{}, false, err
-				}
-				aIn1, err := hmysolidityio.DecodeInt(txLog.Data, 1)
-				if err != nil {
-					return types.Swap{}, false, err
-				}
-				inputAmount = inputAmount.Or(aIn0, aIn1)
-				s.InAmount = inputAmount
+				s.InAmount = new(big.Int).Or(amounts[0].(*big.Int), amounts[1].(*big.Int))
 			}
 			pathLeft--
 			// If it's the last swap of the path read output amount
 			if pathLeft == 0 {
-				outputAmount := big.NewInt(0)
-				aOut0, err := hmysolidityio.DecodeInt(txLog.Data, 2)
-				if err != nil {
-					return types.Swap{}, false, err
-				}
-				aOut1, err := hmysolidityio.DecodeInt(txLog.Data, 3)
-				if err != nil {
-					return types.Swap{}, false, err
-				}
-				outputAmount = outputAmount.Or(aOut0, aOut1)
-				s.OutAmount = outputAmount
+				s.OutAmount = new(big.Int).Or(amounts[2].(*big.Int), amounts[3].(*big.Int))
 				return s, true, nil
 			}
 		}
@@ -158,7 +144,10 @@ path:
 func AnalyzeFees(swap *types.Swap, reserves []types.HistoricLiquidityRatio, tx types.Transaction) (err error) {
 	side := getVariableSide(tx.Method.Signature)
 	pathOffset := getPathOffset(tx.Method.Signature)
-	tokenPath, err := hmysolidityio.DecodeArray(tx.Input[8:], pathOffset)
+	tokenPath, err := hmysolidityio.DecodeArray(tx.Input, pathOffset)
+	if err != nil {
+		return
+	}
 	switch side {
 	case 1:
 		swap.FeeToken = swap.InToken.Address.OneAddress
@@ -0,0 +1,121 @@
+package hmyload
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math"
+)
+
+const (
+	blockByNumberMethod = "hmyv2_getBlockByNumber"
+)
+
+// GetBlocks returns every block from one block number to another, both included
+func (l *Loader) GetBlocks(from, to uint64) (blocks []types.Block, err error) {
+	return l.GetBlocksContext(context.Background(), from, to)
+}
+
+// GetBlocksContext is GetBlocks but stops in-flight batches and returns ctx.Err() once ctx is done.
+// Blocks are requested in chunks of Opts.TransactionChunkSize
+func (l *Loader) GetBlocksContext(ctx context.Context, from, to uint64) (blocks []types.Block, err error) {
+	if to < from {
+		return nil, errors.Errorf("block range %d to %d is empty", from, to)
+	}
+	// Prepare requests
+	blocks = make([]types.Block, to-from+1)
+	found := make([]bool, len(blocks))
+	bodiesByConn, idx, foundInCache := make([][]rpc.Body, l.uniqueConnCount), 0, 0
+	for num := from; num <= to; num++ {
+		if b, ok := l.cache.GetBlock(l.shardId, num); ok {
+			// Cache hit
+			blocks[num-from], found[num-from] = *b, true
+			foundInCache++
+		} else {
+			// Cache miss
+			b := l.uniqueConns[idx].NewBody(blockByNumberMethod, num, map[string]bool{
+				"fullTx":      false,
+				"inclTx":      true,
+				"inclStaking": true,
+			})
+			bodiesByConn[idx] = append(bodiesByConn[idx], b)
+			idx++
+			if idx == l.uniqueConnCount {
+				idx = 0
+			}
+		}
+	}
+	// Do requests across unique nodes
+	ch := make(chan goBlock, len(blocks)-foundInCache)
+	for i, conn := range l.uniqueConns {
+		go func(conn rpc.Client, bodies []rpc.Body) {
+			// Split into chunks to avoid node stress
+			for start := 0; start < len(bodies); start += l.txChunkSize {
+				chunk := bodies[start:int(math.Min(float64(len(bodies)), float64(start+l.txChunkSize)))]
+				ress, err := conn.RawBatchCallContext(ctx, chunk)
+				if err != nil {
+					ch <- goBlock{err: err}
+					return
+				}
+				for i, res := range ress {
+					b, err := readBlockFromResponse(res)
+					if err != nil {
+						ch <- goBlock{err: err}
+						return
+					}
+					if b == nil {
+						ch <- goBlock{err: errors.Errorf("block %v was not found", chunk[i].Params[0])}
+						return
+					}
+					ch <- goBlock{
+						err:   nil,
+						block: b,
+					}
+				}
+			}
+		}(conn, bodiesByConn[i])
+	}
+	// Read output
+	for i := foundInCache; i < len(blocks); i++ {
+		var out goBlock
+		select {
+		case out = <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if out.err != nil {
+			return nil, out.err
+		}
+		out.block.ShardID = l.shardId
+		if out.block.Number < from || out.block.Number > to || found[out.block.Number-from] {
+			return nil, errors.Errorf("node returned unexpected block %d", out.block.Number)
+		}
+		blocks[out.block.Number-from], found[out.block.Number-from] = *out.block, true
+		l.cache.SetBlock(out.block)
+	}
+	return
+}
+
+func readBlockFromResponse(data []byte) (b *types.Block, err error) {
+	// Blocks which were not produced yet are null
+	if string(data) == "null" {
+		return nil, nil
+	}
+	var j blockJson
+	err = json.Unmarshal(data, &j)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	b = &types.Block{
+		Number:          j.Number,
+		Hash:            j.Hash,
+		Timestamp:       j.Timestamp,
+		Leader:          types.NewAddress(j.Miner),
+		GasUsed:         j.GasUsed,
+		TxHashes:        j.TxHashes,
+		StakingTxHashes: j.StakingTxHashes,
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package hmyload
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
+)
+
+const (
+	cxReceiptMethod = "hmyv2_getCXReceiptByHash"
+)
+
+// GetCrossShardTransfers joins every given transaction that sends funds to another shard with its receipt on that shard.
+// Wallet histories only contain the sending transaction, pass them here to find out whether and when the funds arrived.
+// Transactions staying on their shard are skipped. The destination shards must be known to the Loader
+func (l *Loader) GetCrossShardTransfers(txs ...types.Transaction) (transfers []types.CrossShardTransfer, err error) {
+	return l.GetCrossShardTransfersContext(context.Background(), txs...)
+}
+
+// GetCrossShardTransfersContext is GetCrossShardTransfers but stops waiting and returns ctx.Err() once ctx is done
+func (l *Loader) GetCrossShardTransfersContext(ctx context.Context, txs ...types.Transaction) (transfers []types.CrossShardTransfer, err error) {
+	// Group transfers by destination shard
+	idxByShard := map[uint][]int{}
+	for _, tx := range txs {
+		if tx.ShardID == tx.ToShardID {
+			continue
+		}
+		if _, ok := l.shards[tx.ToShardID]; !ok {
+			return nil, errors.Errorf("no endpoint for shard %d known to resolve transaction %s", tx.ToShardID, tx.TxHash)
+		}
+		idxByShard[tx.ToShardID] = append(idxByShard[tx.ToShardID], len(transfers))
+		transfers = append(transfers, types.CrossShardTransfer{Tx: tx})
+	}
+	// Look up receipts on each destination shard
+	for id, idxs := range idxByShard {
+		shard := l.shards[id]
+		bodies := make([]rpc.Body, len(idxs))
+		for i, idx := range idxs {
+			bodies[i] = shard.defaultConn.NewBody(cxReceiptMethod, transfers[idx].Tx.TxHash)
+		}
+		ress, err := shard.defaultConn.RawBatchCallContext(ctx, bodies)
+		if err != nil {
+			return nil, err
+		}
+		for i, idx := range idxs {
+			transfers[idx].Receipt, err = readCXReceiptFromResponse(ress[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+func readCXReceiptFromResponse(data []byte) (r *types.CXReceipt, err error) {
+	// Transfers which did not arrive yet have no receipt
+	if string(data) == "null" {
+		return nil, nil
+	}
+	var c cxReceiptJson
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	amount := new(big.Int)
+	amount.SetString(c.Value.String(), 10)
+	r = &types.CXReceipt{
+		TxHash:    c.TxHash,
+		BlockHash: c.BlockHash,
+		BlockNum:  c.BlockNum,
+		Sender:    types.NewAddress(c.Sender),
+		Receiver:  types.NewAddress(c.Receiver),
+		Amount:    amount,
+		ShardID:   c.ShardID,
+		ToShardID: c.ToShardID,
+	}
+	return
+}
@@ -2,9 +2,12 @@ package hmyload
 
 import (
 	"encoding/json"
+	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/cache"
+	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
 	"github.com/mjmar01/harmolytics/pkg/rpc"
 	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
 	"time"
 )
 
@@ -12,28 +15,73 @@ import (
 
 // Loader struct used to load blockchain data
 type Loader struct {
-	defaultConn     *rpc.RPC
-	optionalConns   []*rpc.RPC
-	connCount       int
+	shardId         uint
+	shards          map[uint]*Loader
+	defaultConn     rpc.Client
+	conns           []rpc.Client
+	sharedConns     bool
 	uniqueConnCount int
-	connByPeer      map[string][]*rpc.RPC
-	uniqueConns     []*rpc.RPC
+	uniqueConns     []rpc.Client
 	cache           *cache.Cache
 	sharedCache     bool
+	resolver        hmysolidityio.SignatureResolver
+	// Load shaping
+	historyPageSize int
+	txChunkSize     int
+	logBlockRange   int
 }
 
 // Opts contains optional parameters for the NewLoader function
 type Opts struct {
 	// Loader settings
 	AdditionalConnections int
+	HistoryPageSize       int // Entries per hmyv2_getTransactionsHistory page. Defaults to 50000
+	LogBlockRange         int // Blocks queried at once by GetLogs. Halved while the node refuses, doubled again after successes. Defaults to 1024
+	TransactionChunkSize  int // Hashes loaded at once by GetTransactionsByWallet and blocks by GetBlocks. Defaults to 5000
+	// Rate limits are applied per peer. Zero means unlimited
+	RequestsPerSecond float64 // Requests (single calls or batch chunks) sent per second
+	MaxInFlight       int     // Bodies waiting for a reply at the same time. Larger batches are sent in chunks
 	// RPC settings
-	RpcTimeout time.Duration
+	RpcTimeout      time.Duration
+	RpcRetry        *rpc.RetryPolicy
+	ExistingClients []rpc.Client
+	// Shard settings. Endpoints of shards other than 0, clients are used instead of connecting to the URL
+	ShardUrls    map[uint]string
+	ShardClients map[uint][]rpc.Client
+	// Method lookup. Defaults to the bundled signatures followed by 4byte.directory
+	SignatureResolver hmysolidityio.SignatureResolver
 	// Cache settings
 	CacheDir                 string
 	ExistingCache            *cache.Cache
 	PreLoadCacheTransactions bool
 }
 
+// LogFilter selects event logs for Loader.GetLogs
+type LogFilter struct {
+	FromBlock uint64
+	ToBlock   uint64 // 0 means the latest block
+	// Logs emitted by any of the addresses. Empty matches every address
+	Addresses []types.Address
+	// Topics by position. Each position matches any of its topics, an empty position matches every topic
+	Topics [][]string
+}
+
+// WalletProgress is reported by Loader.StreamTransactionsByWallet. Counts add up over all shards
+type WalletProgress struct {
+	PagesFetched int // History pages fetched so far
+	Pages        int // Expected number of history pages
+	TxsLoaded    int // Transactions sent to the stream so far
+	Txs          int // Unique transactions of the wallet. Known once all pages are fetched
+	CacheHits    int // Loaded transactions that were found in the cache
+}
+
+// TokenErrors is returned by GetTokens if at least one token could not be read.
+// It holds one entry per given address, entries of loaded tokens are nil. Addresses without token contract have ErrNotToken
+type TokenErrors []error
+
+// ErrNotToken marks addresses which don't answer decimals(), e.g. wallets or other contracts
+var ErrNotToken = errors.New("address is not a token")
+
 func defaults(in *Opts) (out *Opts) {
 	if in == nil {
 		out = new(Opts)
@@ -43,6 +91,21 @@ func defaults(in *Opts) (out *Opts) {
 	if out.AdditionalConnections == 0 {
 		out.AdditionalConnections = 1
 	}
+	if out.HistoryPageSize == 0 {
+		out.HistoryPageSize = 50000
+	}
+	if out.TransactionChunkSize == 0 {
+		out.TransactionChunkSize = 5000
+	}
+	if out.LogBlockRange == 0 {
+		out.LogBlockRange = 1024
+	}
+	if out.SignatureResolver == nil {
+		out.SignatureResolver = hmysolidityio.ChainResolver{
+			hmysolidityio.BundledResolver(),
+			hmysolidityio.NewFourByteResolver(""),
+		}
+	}
 	return
 }
 
@@ -75,6 +138,62 @@ type transactionLogJson struct {
 	Data     string   `json:"data"`
 	Address  string   `json:"address"`
 	LogIndex string   `json:"logIndex"`
+	BlockNum string   `json:"blockNumber"`
+	TxHash   string   `json:"transactionHash"`
+	Removed  bool     `json:"removed"`
+}
+
+type headerJson struct {
+	Number    string `json:"number"`
+	Hash      string `json:"hash"`
+	Timestamp string `json:"timestamp"`
+	Miner     string `json:"miner"`
+	GasUsed   string `json:"gasUsed"`
+}
+
+type blockJson struct {
+	Number          uint64   `json:"number"`
+	Hash            string   `json:"hash"`
+	Timestamp       uint64   `json:"timestamp"`
+	Miner           string   `json:"miner"`
+	GasUsed         uint64   `json:"gasUsed"`
+	TxHashes        []string `json:"transactions"`
+	StakingTxHashes []string `json:"stakingTransactions"`
+}
+
+type cxReceiptJson struct {
+	TxHash    string      `json:"hash"`
+	BlockHash string      `json:"blockHash"`
+	BlockNum  uint64      `json:"blockNumber"`
+	Sender    string      `json:"from"`
+	Receiver  string      `json:"to"`
+	Value     json.Number `json:"value"`
+	ShardID   uint        `json:"shardID"`
+	ToShardID uint        `json:"toShardID"`
+}
+
+// stakingTransactionJson covers all staking types. Fields not used by a type stay empty
+type stakingTransactionJson struct {
+	TxHash    string      `json:"hash"`
+	Type      string      `json:"type"`
+	Sender    string      `json:"from"`
+	Timestamp uint64      `json:"timestamp"`
+	GasAmount uint64      `json:"gas"`
+	GasPrice  json.Number `json:"gasPrice"`
+	BlockNum  uint64      `json:"blockNumber"`
+	Msg       struct {
+		Amount    json.Number `json:"amount"`
+		Delegator string      `json:"delegatorAddress"`
+		Validator string      `json:"validatorAddress"`
+	} `json:"msg"`
+}
+
+type transactionHistoryJson struct {
+	Transactions []string `json:"transactions"`
+}
+
+type stakingHistoryJson struct {
+	StakingTransactions []string `json:"staking_transactions"`
 }
 
 // goFunc returns
@@ -83,9 +202,34 @@ type goTx struct {
 	tx  *types.Transaction
 }
 
-type goTk struct {
+type goStakingTx struct {
 	err error
-	tk  types.Token
+	tx  *types.StakingTransaction
+}
+
+type goBlock struct {
+	err   error
+	block *types.Block
+}
+
+type goTk struct {
+	err   error
+	idx   int
+	tk    types.Token
+	tkErr error
+}
+
+type goBalance struct {
+	err     error
+	idx     int
+	balance *big.Int
+}
+
+type goReserves struct {
+	err      error
+	idx      int
+	reserve0 *big.Int
+	reserve1 *big.Int
 }
 
 //</editor-fold>
@@ -0,0 +1,124 @@
+package hmyload
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/types"
+)
+
+// Enrich fills Name, Symbol and Decimals of every token in the given decoded results.
+// Accepted are pointers to types.Token, types.TokenTransaction, types.Swap, types.Claim, types.LiquidityAction
+// and types.LiquidityPool as well as slices of these types. Tokens are loaded in one batch and cached.
+// If some tokens can't be loaded the others are still filled and TokenErrors is returned
+func (l *Loader) Enrich(results ...interface{}) (err error) {
+	return l.EnrichContext(context.Background(), results...)
+}
+
+// EnrichContext is Enrich but stops loading and returns ctx.Err() once ctx is done
+func (l *Loader) EnrichContext(ctx context.Context, results ...interface{}) (err error) {
+	// Collect tokens of all results
+	var tks []*types.Token
+	for _, result := range results {
+		tks, err = collectTokens(tks, result)
+		if err != nil {
+			return err
+		}
+	}
+	// Load all tokens at once, known ones are read from the cache
+	var addrs []types.Address
+	seen := map[string]bool{}
+	for _, tk := range tks {
+		if !seen[tk.Address.OneAddress] {
+			addrs = append(addrs, tk.Address)
+			seen[tk.Address.OneAddress] = true
+		}
+	}
+	if len(addrs) == 0 {
+		return
+	}
+	loaded, err := l.GetTokensContext(ctx, addrs...)
+	var tkErrs TokenErrors
+	if err != nil && !errors.As(err, &tkErrs) {
+		return err
+	}
+	known := make(map[string]types.Token, len(loaded))
+	for i, tk := range loaded {
+		if tkErrs == nil || tkErrs[i] == nil {
+			known[tk.Address.OneAddress] = tk
+		}
+	}
+	// Fill results, tokens which failed to load stay as they are
+	for _, tk := range tks {
+		if knownTk, ok := known[tk.Address.OneAddress]; ok {
+			*tk = knownTk
+		}
+	}
+	return err
+}
+
+// collectTokens appends pointers to all tokens of result which only have their address set
+func collectTokens(tks []*types.Token, result interface{}) ([]*types.Token, error) {
+	switch r := result.(type) {
+	case *types.Token:
+		tks = appendToken(tks, r)
+	case *types.TokenTransaction:
+		tks = appendToken(tks, &r.Token)
+	case *types.Swap:
+		tks = appendSwap(tks, r)
+	case *types.Claim:
+		tks = appendToken(tks, &r.Token)
+	case *types.LiquidityAction:
+		tks = appendPool(tks, &r.LP)
+	case *types.LiquidityPool:
+		tks = appendPool(tks, r)
+	case []types.Token:
+		for i := range r {
+			tks = appendToken(tks, &r[i])
+		}
+	case []types.TokenTransaction:
+		for i := range r {
+			tks = appendToken(tks, &r[i].Token)
+		}
+	case []types.Swap:
+		for i := range r {
+			tks = appendSwap(tks, &r[i])
+		}
+	case []types.Claim:
+		for i := range r {
+			tks = appendToken(tks, &r[i].Token)
+		}
+	case []types.LiquidityAction:
+		for i := range r {
+			tks = appendPool(tks, &r[i].LP)
+		}
+	case []types.LiquidityPool:
+		for i := range r {
+			tks = appendPool(tks, &r[i])
+		}
+	default:
+		return nil, errors.Errorf("can't enrich %T", result)
+	}
+	return tks, nil
+}
+
+func appendToken(tks []*types.Token, tk *types.Token) []*types.Token {
+	if tk.Address.OneAddress == "" || tk.Name != "" || tk.Symbol != "" {
+		return tks
+	}
+	return append(tks, tk)
+}
+
+func appendSwap(tks []*types.Token, s *types.Swap) []*types.Token {
+	tks = appendToken(tks, &s.InToken)
+	tks = appendToken(tks, &s.OutToken)
+	for i := range s.Path {
+		tks = appendPool(tks, &s.Path[i])
+	}
+	return tks
+}
+
+func appendPool(tks []*types.Token, lp *types.LiquidityPool) []*types.Token {
+	tks = appendToken(tks, &lp.TokenA)
+	tks = appendToken(tks, &lp.TokenB)
+	return appendToken(tks, &lp.LpToken)
+}
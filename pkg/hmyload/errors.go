@@ -0,0 +1,19 @@
+package hmyload
+
+import "fmt"
+
+func (e TokenErrors) Error() string {
+	failed, first := 0, -1
+	for i, err := range e {
+		if err != nil {
+			failed++
+			if first == -1 {
+				first = i
+			}
+		}
+	}
+	if failed == 0 {
+		return "no token errors"
+	}
+	return fmt.Sprintf("%d of %d tokens failed, first at position %d: %s", failed, len(e), first, e[first].Error())
+}
@@ -4,20 +4,67 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/cache"
 	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"sort"
 )
 
-// NewLoader creates a struct to load blockchain data
+// NewLoader creates a struct to load blockchain data.
+// url is the endpoint of shard 0, other shards are loaded if Opts.ShardUrls or Opts.ShardClients are given.
+// If Opts.ExistingClients are given they are used instead of connecting to url
 func NewLoader(url string, opts *Opts) (l *Loader, err error) {
 	opts = defaults(opts)
+	l, err = newShardLoader(0, url, opts.ExistingClients, opts)
+	if err != nil {
+		return nil, err
+	}
+	l.shards = map[uint]*Loader{0: l}
+
+	// Other shards share the cache of shard 0
+	shardOpts := *opts
+	shardOpts.ExistingCache = l.cache
+	for id, clients := range opts.ShardClients {
+		if id == 0 || l.shards[id] != nil {
+			continue
+		}
+		l.shards[id], err = newShardLoader(id, opts.ShardUrls[id], clients, &shardOpts)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	for id, shardUrl := range opts.ShardUrls {
+		if id == 0 || l.shards[id] != nil {
+			continue
+		}
+		l.shards[id], err = newShardLoader(id, shardUrl, nil, &shardOpts)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return
+}
+
+func newShardLoader(shardId uint, url string, clients []rpc.Client, opts *Opts) (l *Loader, err error) {
 	l = new(Loader)
+	l.shardId = shardId
 
 	// Create RPCs
-	rs, err := rpc.NewRPCs(url, opts.AdditionalConnections, &rpc.Opts{Timeout: opts.RpcTimeout})
-	if err != nil {
-		return nil, errors.Wrap(err, 0)
+	if len(clients) > 0 {
+		l.conns = append([]rpc.Client{}, clients...)
+		l.sharedConns = true
+	} else {
+		rs, err := rpc.NewRPCs(url, opts.AdditionalConnections, &rpc.Opts{Timeout: opts.RpcTimeout, Retry: opts.RpcRetry})
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		for _, r := range rs {
+			l.conns = append(l.conns, r)
+		}
 	}
-	l.optionalConns = rs
-	l.defaultConn = rs[0]
+	l.historyPageSize = opts.HistoryPageSize
+	l.txChunkSize = opts.TransactionChunkSize
+	l.logBlockRange = opts.LogBlockRange
+	l.resolver = opts.SignatureResolver
 
 	// Open cache
 	if opts.ExistingCache != nil {
@@ -30,25 +77,58 @@ func NewLoader(url string, opts *Opts) (l *Loader, err error) {
 		})
 	}
 
-	// Fill Loader metadata
-	l.connByPeer = make(map[string][]*rpc.RPC)
-	for _, conn := range l.optionalConns {
-		l.connByPeer[conn.PeerId] = append(l.connByPeer[conn.PeerId], conn)
-	}
-	l.uniqueConns = make([]*rpc.RPC, len(l.connByPeer))
-	idx := 0
-	for _, rpcs := range l.connByPeer {
-		l.uniqueConns[idx] = rpcs[0]
-		idx++
+	// Fill Loader metadata. Connections can be shared so only one per peer is needed
+	limiters := map[string]*limiter{}
+	for i, conn := range l.conns {
+		lim, ok := limiters[conn.PeerId()]
+		if !ok {
+			lim = newLimiter(opts.RequestsPerSecond, opts.MaxInFlight)
+			limiters[conn.PeerId()] = lim
+		}
+		if opts.RequestsPerSecond > 0 || opts.MaxInFlight > 0 {
+			// All connections to a peer share its limits
+			l.conns[i] = newLimitedConn(conn, lim)
+		}
+		if !ok {
+			l.uniqueConns = append(l.uniqueConns, l.conns[i])
+		}
 	}
 	l.uniqueConnCount = len(l.uniqueConns)
+	l.defaultConn = l.conns[0]
+	return
+}
+
+// Shard returns the Loader of the given shard. It is closed together with l and must not be closed on its own
+func (l *Loader) Shard(id uint) (shard *Loader, ok bool) {
+	shard, ok = l.shards[id]
+	return
+}
+
+// shardLoaders returns the loaders of all shards ordered by shard ID
+func (l *Loader) shardLoaders() (shards []*Loader) {
+	if l.shards == nil {
+		return []*Loader{l}
+	}
+	for _, shard := range l.shards {
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(i, j int) bool {
+		return shards[i].shardId < shards[j].shardId
+	})
 	return
 }
 
+// Close closes all connections opened by the Loader. Existing clients passed via Opts are left open
 func (l *Loader) Close() {
-	l.defaultConn.Close()
-	for _, conn := range l.optionalConns {
-		conn.Close()
+	for id, shard := range l.shards {
+		if id != l.shardId {
+			shard.Close()
+		}
+	}
+	if !l.sharedConns {
+		for _, conn := range l.conns {
+			conn.Close()
+		}
 	}
 	l.cache.Done()
 }
@@ -0,0 +1,164 @@
+package hmyload
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"sync"
+	"time"
+)
+
+// limiter shapes the load put on a single peer. It is shared by all connections to that peer
+type limiter struct {
+	// Minimum time between two requests
+	interval  time.Duration
+	next      time.Time
+	nextMutex sync.Mutex
+	// Every body in flight holds one slot
+	slots        chan struct{}
+	acquireMutex sync.Mutex
+}
+
+// limitedConn is a rpc.Client whose calls go through the limiter of its peer.
+// Batches larger than the in-flight limit are sent in chunks
+type limitedConn struct {
+	rpc.Calls
+	client  rpc.Client
+	limiter *limiter
+}
+
+func newLimitedConn(conn rpc.Client, lim *limiter) *limitedConn {
+	c := &limitedConn{client: conn, limiter: lim}
+	c.Calls = rpc.NewCalls(c)
+	return c
+}
+
+func newLimiter(requestsPerSecond float64, maxInFlight int) *limiter {
+	lim := new(limiter)
+	if requestsPerSecond > 0 {
+		lim.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	if maxInFlight > 0 {
+		lim.slots = make(chan struct{}, maxInFlight)
+	}
+	return lim
+}
+
+// acquire waits for a request to be allowed and n bodies to fit in flight
+func (lim *limiter) acquire(ctx context.Context, n int) (err error) {
+	if lim.slots != nil {
+		// Only one caller collects slots at a time, so partially filled requests can't block each other
+		lim.acquireMutex.Lock()
+		for i := 0; i < n; i++ {
+			select {
+			case lim.slots <- struct{}{}:
+			case <-ctx.Done():
+				lim.acquireMutex.Unlock()
+				lim.release(i)
+				return ctx.Err()
+			}
+		}
+		lim.acquireMutex.Unlock()
+	}
+	if lim.interval > 0 {
+		lim.nextMutex.Lock()
+		now := time.Now()
+		if lim.next.Before(now) {
+			lim.next = now
+		}
+		wait := lim.next.Sub(now)
+		lim.next = lim.next.Add(lim.interval)
+		lim.nextMutex.Unlock()
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				lim.release(n)
+				return ctx.Err()
+			}
+		}
+	}
+	return
+}
+
+func (lim *limiter) release(n int) {
+	if lim.slots == nil {
+		return
+	}
+	for i := 0; i < n; i++ {
+		<-lim.slots
+	}
+}
+
+// chunkSize returns how many bodies may be sent at once
+func (lim *limiter) chunkSize(n int) int {
+	if lim.slots != nil && cap(lim.slots) < n {
+		return cap(lim.slots)
+	}
+	return n
+}
+
+func (c *limitedConn) RawCallContext(ctx context.Context, method string, params ...interface{}) (result []byte, err error) {
+	err = c.limiter.acquire(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer c.limiter.release(1)
+	return c.client.RawCallContext(ctx, method, params...)
+}
+
+func (c *limitedConn) RawBatchCallContext(ctx context.Context, bodies []rpc.Body) (results [][]byte, err error) {
+	results = make([][]byte, len(bodies))
+	var batchErr rpc.BatchError
+	size := c.limiter.chunkSize(len(bodies))
+	for start := 0; start < len(bodies); start += size {
+		end := start + size
+		if end > len(bodies) {
+			end = len(bodies)
+		}
+		err = c.limiter.acquire(ctx, end-start)
+		if err != nil {
+			return nil, err
+		}
+		var ress [][]byte
+		ress, err = c.client.RawBatchCallContext(ctx, bodies[start:end])
+		c.limiter.release(end - start)
+		var chunkErr rpc.BatchError
+		if err != nil && !errors.As(err, &chunkErr) {
+			return nil, err
+		}
+		copy(results[start:end], ress)
+		if chunkErr != nil {
+			if batchErr == nil {
+				batchErr = make(rpc.BatchError, len(bodies))
+			}
+			copy(batchErr[start:end], chunkErr)
+		}
+	}
+	if batchErr != nil {
+		return results, errors.Wrap(batchErr, 0)
+	}
+	return results, nil
+}
+
+func (c *limitedConn) Subscribe(ctx context.Context, params ...interface{}) (s *rpc.Subscription, err error) {
+	sub, ok := c.client.(rpc.Subscriber)
+	if !ok {
+		return nil, errors.Errorf("client does not support subscriptions")
+	}
+	return sub.Subscribe(ctx, params...)
+}
+
+func (c *limitedConn) NewBody(method string, params ...interface{}) rpc.Body {
+	return c.client.NewBody(method, params...)
+}
+
+func (c *limitedConn) Close() {
+	c.client.Close()
+}
+
+func (c *limitedConn) PeerId() string {
+	return c.client.PeerId()
+}
@@ -0,0 +1,144 @@
+package hmyload
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math"
+	"strings"
+)
+
+const (
+	logsMethod        = "eth_getLogs"
+	blockNumberMethod = "hmyv2_blockNumber"
+)
+
+// logRangeErrors are parts of the messages nodes refuse large eth_getLogs queries with
+var logRangeErrors = []string{"smaller than size", "block range", "more than", "too many results", "too many logs", "response size"}
+
+// GetLogs returns all event logs matching the filter in ascending order.
+// The block range is queried in windows of Opts.LogBlockRange blocks, windows the node refuses are split
+func (l *Loader) GetLogs(filter LogFilter) (logs []types.TransactionLog, err error) {
+	return l.GetLogsContext(context.Background(), filter)
+}
+
+// GetLogsContext is GetLogs but stops loading and returns ctx.Err() once ctx is done
+func (l *Loader) GetLogsContext(ctx context.Context, filter LogFilter) (logs []types.TransactionLog, err error) {
+	to := filter.ToBlock
+	if to == 0 {
+		c, err := l.defaultConn.CallContext(ctx, blockNumberMethod)
+		if err != nil {
+			return nil, err
+		}
+		f, ok := c.(float64)
+		if !ok || f < 0 {
+			return nil, errors.Errorf("%v is not a block number", c)
+		}
+		to = uint64(f)
+	}
+	if to < filter.FromBlock {
+		return nil, errors.Errorf("block range %d to %d is empty", filter.FromBlock, to)
+	}
+	addrs, topics := filter.params()
+	// Page through the range, halving the window whenever the node refuses it and growing it back after successes
+	window := uint64(l.logBlockRange)
+	for from := filter.FromBlock; from <= to; {
+		end := from + window - 1
+		if end > to {
+			end = to
+		}
+		res, err := l.defaultConn.RawCallContext(ctx, logsMethod, map[string]interface{}{
+			"fromBlock": hexutil.EncodeUint64(from),
+			"toBlock":   hexutil.EncodeUint64(end),
+			"address":   addrs,
+			"topics":    topics,
+		})
+		if isRangeError(err) && window > 1 {
+			window /= 2
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		windowLogs, err := readLogsFromResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, windowLogs...)
+		from = end + 1
+		if window < uint64(l.logBlockRange) {
+			window = uint64(math.Min(float64(window*2), float64(l.logBlockRange)))
+		}
+	}
+	return
+}
+
+// isRangeError tells if the node refused a block range because it is too large or has too many results
+func isRangeError(err error) bool {
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	for _, s := range logRangeErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func readLogsFromResponse(data []byte) (ls []types.TransactionLog, err error) {
+	var logs []transactionLogJson
+	err = json.Unmarshal(data, &logs)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	ls = make([]types.TransactionLog, len(logs))
+	for i, l := range logs {
+		ls[i], err = readLog(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func readLog(l transactionLogJson) (log types.TransactionLog, err error) {
+	// Convert data formats
+	index, err := hexutil.DecodeUint64(l.LogIndex)
+	if err != nil {
+		return types.TransactionLog{}, errors.Wrap(err, 0)
+	}
+	blockNum, err := hexutil.DecodeUint64(l.BlockNum)
+	if err != nil {
+		return types.TransactionLog{}, errors.Wrap(err, 0)
+	}
+	log = types.TransactionLog{
+		TxHash:   l.TxHash,
+		LogIndex: int(index),
+		BlockNum: blockNum,
+		Address:  types.NewAddress(l.Address),
+		Topics:   l.Topics,
+		Data:     l.Data,
+	}
+	return
+}
+
+// params converts addresses and topics to eth_getLogs and eth_subscribe filter params
+func (f LogFilter) params() (addrs []string, topics []interface{}) {
+	addrs = make([]string, len(f.Addresses))
+	for i, addr := range f.Addresses {
+		addrs[i] = addr.HexAddress
+	}
+	topics = make([]interface{}, len(f.Topics))
+	for i, position := range f.Topics {
+		if len(position) > 0 {
+			topics[i] = position
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package hmyload
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/hmydecode"
+	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
+	"strings"
+)
+
+const (
+	getReservesMethod = "0x0902f1ac"
+)
+
+// GetReserves returns the reserves of each pool as they were before blockNum, i.e. at the end of block blockNum-1.
+// Ratios are in the order of pools, ReserveA belongs to TokenA of the pool
+func (l *Loader) GetReserves(pools []types.LiquidityPool, blockNum uint64) (ratios []types.HistoricLiquidityRatio, err error) {
+	return l.GetReservesContext(context.Background(), pools, blockNum)
+}
+
+// GetReservesContext is GetReserves but stops in-flight batches and returns ctx.Err() once ctx is done
+func (l *Loader) GetReservesContext(ctx context.Context, pools []types.LiquidityPool, blockNum uint64) (ratios []types.HistoricLiquidityRatio, err error) {
+	if blockNum == 0 {
+		return nil, errors.Errorf("there are no reserves before block 0")
+	}
+	block := blockNum - 1
+	// Prepare requests across unique peers
+	ratios = make([]types.HistoricLiquidityRatio, len(pools))
+	bodiesByConn, idx, idxsByConn, foundInCache := make([][]rpc.Body, l.uniqueConnCount), 0, make([][]int, l.uniqueConnCount), 0
+	for i, pool := range pools {
+		ratios[i] = types.HistoricLiquidityRatio{LP: pool, BlockNum: block}
+		if reserve0, reserve1, ok := l.cache.GetReserves(pool.LpToken.Address, block); ok {
+			// Cache hit
+			ratios[i].ReserveA, ratios[i].ReserveB = sortReserves(pool, reserve0, reserve1)
+			foundInCache++
+			continue
+		}
+		// Cache miss
+		b := l.uniqueConns[idx].NewBody(callMethod, map[string]string{"to": pool.LpToken.Address.HexAddress, "data": getReservesMethod}, block)
+		bodiesByConn[idx] = append(bodiesByConn[idx], b)
+		idxsByConn[idx] = append(idxsByConn[idx], i)
+		idx++
+		if idx == l.uniqueConnCount {
+			idx = 0
+		}
+	}
+	// Do requests
+	ch := make(chan goReserves, len(pools)-foundInCache)
+	for i, conn := range l.uniqueConns {
+		if len(bodiesByConn[i]) == 0 {
+			continue
+		}
+		go func(conn rpc.Client, bodies []rpc.Body, idxs []int) {
+			ress, err := conn.RawBatchCallContext(ctx, bodies)
+			if err != nil {
+				ch <- goReserves{err: err}
+				return
+			}
+			for i, res := range ress {
+				var data string
+				err = json.Unmarshal(res, &data)
+				if err != nil {
+					ch <- goReserves{err: errors.Wrap(err, 0)}
+					return
+				}
+				// Addresses without code return no data
+				if len(data) < 2+3*64 {
+					ch <- goReserves{err: errors.Errorf("%s returned no reserves", bodies[i].Params[0].(map[string]string)["to"])}
+					return
+				}
+				out := goReserves{idx: idxs[i]}
+				out.reserve0, err = hmysolidityio.DecodeInt(data, 0)
+				if err != nil {
+					ch <- goReserves{err: err}
+					return
+				}
+				out.reserve1, err = hmysolidityio.DecodeInt(data, 1)
+				if err != nil {
+					ch <- goReserves{err: err}
+					return
+				}
+				ch <- out
+			}
+		}(conn, bodiesByConn[i], idxsByConn[i])
+	}
+	// Read output
+	for i := foundInCache; i < len(pools); i++ {
+		var out goReserves
+		select {
+		case out = <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if out.err != nil {
+			return nil, out.err
+		}
+		pool := pools[out.idx]
+		ratios[out.idx].ReserveA, ratios[out.idx].ReserveB = sortReserves(pool, out.reserve0, out.reserve1)
+		l.cache.SetReserves(pool.LpToken.Address, block, out.reserve0, out.reserve1)
+	}
+	return
+}
+
+// AnalyzeFees loads the reserves of the swap path before the block of tx and fills FeeToken and FeeAmount of swap
+func (l *Loader) AnalyzeFees(swap *types.Swap, tx types.Transaction) (err error) {
+	return l.AnalyzeFeesContext(context.Background(), swap, tx)
+}
+
+// AnalyzeFeesContext is AnalyzeFees but stops loading and returns ctx.Err() once ctx is done
+func (l *Loader) AnalyzeFeesContext(ctx context.Context, swap *types.Swap, tx types.Transaction) (err error) {
+	reserves, err := l.GetReservesContext(ctx, swap.Path, tx.BlockNum)
+	if err != nil {
+		return err
+	}
+	return hmydecode.AnalyzeFees(swap, reserves, tx)
+}
+
+// sortReserves returns the reserves of TokenA and TokenB. Pool contracts order reserves by token address
+func sortReserves(pool types.LiquidityPool, reserve0, reserve1 *big.Int) (reserveA, reserveB *big.Int) {
+	if strings.ToLower(pool.TokenA.Address.HexAddress) < strings.ToLower(pool.TokenB.Address.HexAddress) {
+		return reserve0, reserve1
+	}
+	return reserve1, reserve0
+}
@@ -0,0 +1,211 @@
+package hmyload
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	stakingTransactionByHashMethod  = "hmyv2_getStakingTransactionByHash"
+	stakingTransactionCountMethod   = "hmyv2_getStakingTransactionsCount"
+	stakingTransactionHistoryMethod = "hmyv2_getStakingTransactionsHistory"
+)
+
+// collectRewardsTopic marks the receipt log holding the amount collected by a CollectRewards transaction
+var collectRewardsTopic = crypto.Keccak256Hash([]byte("CollectRewards")).Hex()
+
+// GetStakingTransactionsByWallet returns all staking transactions sent by a given types.Address
+func (l *Loader) GetStakingTransactionsByWallet(addr types.Address) (txs []types.StakingTransaction, err error) {
+	return l.GetStakingTransactionsByWalletContext(context.Background(), addr)
+}
+
+// GetStakingTransactionsByWalletContext is GetStakingTransactionsByWallet but stops loading and returns ctx.Err() once ctx is done
+func (l *Loader) GetStakingTransactionsByWalletContext(ctx context.Context, addr types.Address) (txs []types.StakingTransaction, err error) {
+	c, err := l.defaultConn.CallContext(ctx, stakingTransactionCountMethod, addr.OneAddress, rpc.AllTx)
+	if err != nil {
+		return
+	}
+	n, err := readCount(c)
+	if err != nil {
+		return
+	}
+	txCount := int(n)
+	// Get histories
+	uniqueHashes, hashes := map[string]bool{}, []string{}
+	for page := 0; page*l.historyPageSize < txCount; page++ {
+		res, err := l.defaultConn.RawCallContext(ctx, stakingTransactionHistoryMethod, map[string]interface{}{
+			"address":   addr.OneAddress,
+			"pageIndex": page,
+			"pageSize":  l.historyPageSize,
+			"fullTx":    false,
+			"txType":    rpc.AllTx,
+			"order":     "ASC",
+		})
+		if err != nil {
+			return nil, err
+		}
+		var history stakingHistoryJson
+		err = json.Unmarshal(res, &history)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		for _, hash := range history.StakingTransactions {
+			if !uniqueHashes[hash] {
+				uniqueHashes[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	// Get transactions by hash. Split into chunks to avoid node stress
+	txs = make([]types.StakingTransaction, 0, len(hashes))
+	for i := 0; i < len(hashes); i += l.txChunkSize {
+		size := int(math.Min(float64(len(hashes)-i), float64(l.txChunkSize)))
+		txsPart, err := l.GetFullStakingTransactionsContext(ctx, hashes[i:i+size]...)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, txsPart...)
+	}
+	return
+}
+
+// GetFullStakingTransactions returns staking transactions and their receipts for every given hash
+func (l *Loader) GetFullStakingTransactions(hashes ...string) (txs []types.StakingTransaction, err error) {
+	return l.GetFullStakingTransactionsContext(context.Background(), hashes...)
+}
+
+// GetFullStakingTransactionsContext is GetFullStakingTransactions but stops in-flight batches and returns ctx.Err() once ctx is done
+func (l *Loader) GetFullStakingTransactionsContext(ctx context.Context, hashes ...string) (txs []types.StakingTransaction, err error) {
+	// Prepare requests
+	txs = make([]types.StakingTransaction, len(hashes))
+	txByHash := map[string]*types.StakingTransaction{}
+	bodiesByConn, idx, foundInCache := make([][]rpc.Body, l.uniqueConnCount), 0, 0
+	for _, hash := range hashes {
+		if pTx, ok := l.cache.GetStakingTransaction(hash); ok {
+			// Cache hit
+			txByHash[pTx.TxHash] = pTx
+			foundInCache++
+		} else {
+			// Cache miss
+			b := l.uniqueConns[idx].NewBody(stakingTransactionByHashMethod, hash)
+			bodiesByConn[idx] = append(bodiesByConn[idx], b)
+			b = l.uniqueConns[idx].NewBody(transactionReceiptMethod, hash)
+			bodiesByConn[idx] = append(bodiesByConn[idx], b)
+			idx++
+			if idx == l.uniqueConnCount {
+				idx = 0
+			}
+		}
+	}
+	// Do requests across unique nodes
+	ch := make(chan goStakingTx, len(hashes)-foundInCache)
+	for i, conn := range l.uniqueConns {
+		go func(conn rpc.Client, bodies []rpc.Body) {
+			ress, err := conn.RawBatchCallContext(ctx, bodies)
+			if err != nil {
+				ch <- goStakingTx{err: err}
+				return
+			}
+			// Read each result into a staking transaction
+			for i := 0; i < len(ress); i += 2 {
+				tx, err := readStakingTxFromResponse(ress[i])
+				if err != nil {
+					ch <- goStakingTx{err: err}
+					return
+				}
+				tx.Status, tx.Logs, err = readTxReceiptFromResponse(ress[i+1])
+				if err != nil {
+					ch <- goStakingTx{err: err}
+					return
+				}
+				for j := range tx.Logs {
+					tx.Logs[j].BlockNum = tx.BlockNum
+				}
+				if tx.Type == types.StakingCollectRewards {
+					tx.Amount, err = readCollectedRewards(tx.Logs)
+					if err != nil {
+						ch <- goStakingTx{err: err}
+						return
+					}
+				}
+				ch <- goStakingTx{
+					err: nil,
+					tx:  tx,
+				}
+			}
+		}(conn, bodiesByConn[i])
+	}
+	// Read output
+	for i := foundInCache; i < len(hashes); i++ {
+		var out goStakingTx
+		select {
+		case out = <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if out.err != nil {
+			return nil, out.err
+		}
+		txByHash[out.tx.TxHash] = out.tx
+		l.cache.SetStakingTransaction(out.tx)
+	}
+	for i, hash := range hashes {
+		txPtr, ok := txByHash[hash]
+		if !ok {
+			return nil, errors.Errorf("Given hash (%s) was not found in list of results", hash)
+		}
+		txs[i] = *txPtr
+	}
+	return
+}
+
+func readStakingTxFromResponse(data []byte) (tx *types.StakingTransaction, err error) {
+	// Read JSON into staking transaction
+	var t stakingTransactionJson
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	gasPrice := new(big.Int)
+	gasPrice.SetString(t.GasPrice.String(), 10)
+	amount := new(big.Int)
+	amount.SetString(t.Msg.Amount.String(), 10)
+	tx = &types.StakingTransaction{
+		TxHash:    t.TxHash,
+		Type:      t.Type,
+		Sender:    types.NewAddress(t.Sender),
+		Delegator: types.NewAddress(t.Msg.Delegator),
+		Validator: types.NewAddress(t.Msg.Validator),
+		Amount:    amount,
+		BlockNum:  t.BlockNum,
+		Timestamp: t.Timestamp,
+		GasAmount: uint32(t.GasAmount),
+		GasPrice:  gasPrice,
+	}
+	return
+}
+
+// readCollectedRewards returns the amount logged by a CollectRewards transaction, zero if it failed
+func readCollectedRewards(logs []types.TransactionLog) (amount *big.Int, err error) {
+	amount = new(big.Int)
+	for _, log := range logs {
+		if len(log.Topics) == 0 || !strings.EqualFold(log.Topics[0], collectRewardsTopic) {
+			continue
+		}
+		// The amount is logged as minimal big endian bytes, not as a padded word
+		data, err := hex.DecodeString(strings.TrimPrefix(log.Data, "0x"))
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		amount.SetBytes(data)
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package hmyload
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"github.com/mjmar01/harmolytics/pkg/types"
+)
+
+// SubscribeLogs delivers every new log matching the addresses and topics of the filter, its block range is ignored.
+// Logs dropped by reorgs are skipped. The log channel is closed once ctx is done or the subscription failed,
+// the error channel then yields the failure if any. Subscriptions need a websocket connection
+func (l *Loader) SubscribeLogs(ctx context.Context, filter LogFilter) (<-chan types.TransactionLog, <-chan error, error) {
+	addrs, topics := filter.params()
+	logCh := make(chan types.TransactionLog)
+	errCh, err := l.subscribe(ctx, func(n json.RawMessage) error {
+		var j transactionLogJson
+		err := json.Unmarshal(n, &j)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if j.Removed {
+			return nil
+		}
+		log, err := readLog(j)
+		if err != nil {
+			return err
+		}
+		select {
+		case logCh <- log:
+		case <-ctx.Done():
+		}
+		return nil
+	}, func() {
+		close(logCh)
+	}, "logs", map[string]interface{}{"address": addrs, "topics": topics})
+	if err != nil {
+		return nil, nil, err
+	}
+	return logCh, errCh, nil
+}
+
+// SubscribeNewHeads delivers the header of every new block. Transaction hashes are not part of headers.
+// Channels behave as the ones of SubscribeLogs
+func (l *Loader) SubscribeNewHeads(ctx context.Context) (<-chan types.Block, <-chan error, error) {
+	blockCh := make(chan types.Block)
+	errCh, err := l.subscribe(ctx, func(n json.RawMessage) error {
+		b, err := readHeader(n)
+		if err != nil {
+			return err
+		}
+		b.ShardID = l.shardId
+		select {
+		case blockCh <- b:
+		case <-ctx.Done():
+		}
+		return nil
+	}, func() {
+		close(blockCh)
+	}, "newHeads")
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockCh, errCh, nil
+}
+
+// subscribe hands every notification to handle until ctx is done, handle fails or the subscription ends.
+// stop is called once nothing is handled anymore
+func (l *Loader) subscribe(ctx context.Context, handle func(json.RawMessage) error, stop func(), params ...interface{}) (<-chan error, error) {
+	var subscriber rpc.Subscriber
+	for _, conn := range l.conns {
+		if s, ok := conn.(rpc.Subscriber); ok {
+			subscriber = s
+			break
+		}
+	}
+	if subscriber == nil {
+		return nil, errors.Errorf("no connection of the loader supports subscriptions")
+	}
+	s, err := subscriber.Subscribe(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		defer stop()
+		for {
+			select {
+			case n, ok := <-s.Notifications():
+				if !ok {
+					if err := s.Err(); err != nil {
+						errCh <- err
+					}
+					return
+				}
+				if err := handle(n); err != nil {
+					s.Unsubscribe()
+					errCh <- err
+					return
+				}
+			case <-ctx.Done():
+				s.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return errCh, nil
+}
+
+func readHeader(data []byte) (b types.Block, err error) {
+	var h headerJson
+	err = json.Unmarshal(data, &h)
+	if err != nil {
+		return types.Block{}, errors.Wrap(err, 0)
+	}
+	// Headers use hex numbers
+	b.Number, err = hexutil.DecodeUint64(h.Number)
+	if err != nil {
+		return types.Block{}, errors.Wrap(err, 0)
+	}
+	b.Timestamp, err = hexutil.DecodeUint64(h.Timestamp)
+	if err != nil {
+		return types.Block{}, errors.Wrap(err, 0)
+	}
+	b.GasUsed, err = hexutil.DecodeUint64(h.GasUsed)
+	if err != nil {
+		return types.Block{}, errors.Wrap(err, 0)
+	}
+	b.Hash = h.Hash
+	b.Leader = types.NewAddress(h.Miner)
+	return
+}
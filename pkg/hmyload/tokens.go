@@ -1,9 +1,13 @@
 package hmyload
 
 import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
 	"github.com/mjmar01/harmolytics/pkg/rpc"
 	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
 )
 
 const (
@@ -12,14 +16,31 @@ const (
 	symbolMethod   = "0x95d89b41"
 	decimalsMethod = "0x313ce567"
 	balanceMethod  = "0x70a08231"
+
+	balanceByBlockMethod = "hmyv2_getBalanceByBlockNumber"
 )
 
+// GetTokens returns name, symbol and decimals of the HRC-20 tokens at the given addresses. Known tokens are read from the cache.
+// If some tokens can't be read, the others are still returned together with TokenErrors
 func (l *Loader) GetTokens(addrs ...types.Address) (tks []types.Token, err error) {
+	return l.GetTokensContext(context.Background(), addrs...)
+}
+
+// GetTokensContext is GetTokens but stops in-flight batches and returns ctx.Err() once ctx is done
+func (l *Loader) GetTokensContext(ctx context.Context, addrs ...types.Address) (tks []types.Token, err error) {
 	// Prepare requests across unique peers
 	tks = make([]types.Token, len(addrs))
-	bodiesByConn, idx, addrsByConn := make([][]rpc.Body, l.uniqueConnCount), 0, make([][]types.Address, l.uniqueConnCount)
-	for _, addr := range addrs {
-		addrsByConn[idx] = append(addrsByConn[idx], addr)
+	bodiesByConn, idx, idxsByConn, foundInCache := make([][]rpc.Body, l.uniqueConnCount), 0, make([][]int, l.uniqueConnCount), 0
+	for i, addr := range addrs {
+		if tk, ok := l.cache.GetToken(addr); ok {
+			// Cache hit
+			tks[i] = *tk
+			foundInCache++
+			continue
+		}
+		// Cache miss
+		tks[i].Address = addr
+		idxsByConn[idx] = append(idxsByConn[idx], i)
 		b := l.uniqueConns[idx].NewBody(callMethod, map[string]string{"to": addr.HexAddress, "data": nameMethod}, "latest")
 		bodiesByConn[idx] = append(bodiesByConn[idx], b)
 		b = l.uniqueConns[idx].NewBody(callMethod, map[string]string{"to": addr.HexAddress, "data": symbolMethod}, "latest")
@@ -32,52 +53,190 @@ func (l *Loader) GetTokens(addrs ...types.Address) (tks []types.Token, err error
 		}
 	}
 	// Do requests
-	ch := make(chan goTk, len(addrs))
+	ch := make(chan goTk, len(addrs)-foundInCache)
 	for i, conn := range l.uniqueConns {
-		go func(rpc *rpc.RPC, bodies []rpc.Body, addrs []types.Address) {
-			ress, err := rpc.BatchCall(bodies)
-			if err != nil {
+		if len(bodiesByConn[i]) == 0 {
+			continue
+		}
+		go func(conn rpc.Client, bodies []rpc.Body, idxs []int) {
+			// Reverted calls are answered with errors, they only affect their token
+			ress, err := conn.RawBatchCallContext(ctx, bodies)
+			var batchErr rpc.BatchError
+			if err != nil && !errors.As(err, &batchErr) {
 				ch <- goTk{err: err}
 				return
 			}
 			// Read each result into a token
 			for i := 0; i < len(ress); i += 3 {
-				var tk types.Token
-				tk.Address = addrs[i/3]
-				tk.Name, err = hmysolidityio.DecodeString(ress[i].(string), 0)
-				if err != nil {
-					ch <- goTk{err: err}
-					return
-				}
-				tk.Symbol, err = hmysolidityio.DecodeString(ress[i+1].(string), 0)
-				if err != nil {
-					ch <- goTk{err: err}
-					return
-				}
-				rawDecimals, err := hmysolidityio.DecodeInt(ress[i+2].(string), 0)
-				if err != nil {
-					ch <- goTk{err: err}
-					return
-				}
-				tk.Decimals = int(rawDecimals.Int64())
-				ch <- goTk{
-					err: nil,
-					tk:  tk,
-				}
+				out := goTk{idx: idxs[i/3]}
+				out.tk, out.tkErr = readToken(ress[i : i+3])
+				ch <- out
 			}
-		}(conn, bodiesByConn[i], addrsByConn[i])
+		}(conn, bodiesByConn[i], idxsByConn[i])
 	}
 	// Read Output
-	tkMap := make(map[string]types.Token, len(addrs))
-	for i := 0; i < len(addrs); i++ {
-		out := <-ch
+	var tkErrs TokenErrors
+	for i := foundInCache; i < len(addrs); i++ {
+		var out goTk
+		select {
+		case out = <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 		if out.err != nil {
 			return nil, out.err
 		}
-		tkMap[out.tk.Address.OneAddress] = out.tk
+		if out.tkErr != nil {
+			if tkErrs == nil {
+				tkErrs = make(TokenErrors, len(addrs))
+			}
+			tkErrs[out.idx] = out.tkErr
+			continue
+		}
+		out.tk.Address = addrs[out.idx]
+		tks[out.idx] = out.tk
+		l.cache.SetToken(&out.tk)
 	}
-	for i, addr := range addrs {
-		tks[i] = tkMap[addr.OneAddress]
+	if tkErrs != nil {
+		return tks, errors.Wrap(tkErrs, 0)
+	}
+	return
+}
+
+// readToken reads the results of name, symbol and decimals. Failed calls are nil.
+// Only decimals are required, name and symbol may be missing
+func readToken(ress [][]byte) (tk types.Token, err error) {
+	var data [3]string
+	for i, res := range ress {
+		if res != nil {
+			err = json.Unmarshal(res, &data[i])
+			if err != nil {
+				return types.Token{}, errors.Wrap(err, 0)
+			}
+		}
+	}
+	// Wallets return no data and contracts without decimals revert
+	if len(data[2]) <= 2 {
+		return types.Token{}, ErrNotToken
+	}
+	rawDecimals, err := hmysolidityio.DecodeInt(data[2], 0)
+	if err != nil {
+		return types.Token{}, err
+	}
+	if !rawDecimals.IsInt64() || rawDecimals.Int64() > 255 {
+		return types.Token{}, errors.Errorf("decimals %s are out of range", rawDecimals)
+	}
+	tk.Decimals = int(rawDecimals.Int64())
+	tk.Name, err = readTokenText(data[0])
+	if err != nil {
+		return types.Token{}, err
+	}
+	tk.Symbol, err = readTokenText(data[1])
+	if err != nil {
+		return types.Token{}, err
+	}
+	return
+}
+
+// readTokenText reads a name or symbol. Some older contracts return bytes32 instead of a string
+func readTokenText(data string) (s string, err error) {
+	switch {
+	case len(data) <= 2:
+		return "", nil
+	case len(data) == 2+64:
+		return hmysolidityio.DecodeBytes32String(data, 0)
+	default:
+		return hmysolidityio.DecodeString(data, 0)
+	}
+}
+
+// GetTokenBalances returns the native ONE balance of wallet and its balance of each HRC-20 token at the given block.
+// Token balances are in the order of tokens and in the smallest unit of the token
+func (l *Loader) GetTokenBalances(wallet types.Address, tokens []types.Address, block uint64) (balance *big.Int, tokenBalances []*big.Int, err error) {
+	return l.GetTokenBalancesContext(context.Background(), wallet, tokens, block)
+}
+
+// GetTokenBalancesContext is GetTokenBalances but stops in-flight batches and returns ctx.Err() once ctx is done
+func (l *Loader) GetTokenBalancesContext(ctx context.Context, wallet types.Address, tokens []types.Address, block uint64) (balance *big.Int, tokenBalances []*big.Int, err error) {
+	args, err := hmysolidityio.EncodeAll(wallet)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Prepare requests across unique peers. The native balance is the first body of the first peer, its index is -1
+	bodiesByConn, idx, idxsByConn := make([][]rpc.Body, l.uniqueConnCount), 0, make([][]int, l.uniqueConnCount)
+	bodiesByConn[0] = append(bodiesByConn[0], l.uniqueConns[0].NewBody(balanceByBlockMethod, wallet.OneAddress, block))
+	idxsByConn[0] = append(idxsByConn[0], -1)
+	for i, token := range tokens {
+		b := l.uniqueConns[idx].NewBody(callMethod, map[string]string{"to": token.HexAddress, "data": balanceMethod + args}, block)
+		bodiesByConn[idx] = append(bodiesByConn[idx], b)
+		idxsByConn[idx] = append(idxsByConn[idx], i)
+		idx++
+		if idx == l.uniqueConnCount {
+			idx = 0
+		}
+	}
+	// Do requests
+	ch := make(chan goBalance, len(tokens)+1)
+	for i, conn := range l.uniqueConns {
+		if len(bodiesByConn[i]) == 0 {
+			continue
+		}
+		go func(conn rpc.Client, bodies []rpc.Body, idxs []int) {
+			ress, err := conn.RawBatchCallContext(ctx, bodies)
+			if err != nil {
+				ch <- goBalance{err: err}
+				return
+			}
+			for i, res := range ress {
+				var out goBalance
+				out.idx = idxs[i]
+				if out.idx == -1 {
+					// Native balances are plain numbers
+					out.balance = new(big.Int)
+					err = json.Unmarshal(res, out.balance)
+					if err != nil {
+						ch <- goBalance{err: errors.Wrap(err, 0)}
+						return
+					}
+				} else {
+					var data string
+					err = json.Unmarshal(res, &data)
+					if err != nil {
+						ch <- goBalance{err: errors.Wrap(err, 0)}
+						return
+					}
+					// Addresses without code return no data
+					if len(data) < 66 {
+						ch <- goBalance{err: errors.Errorf("%s returned no balance", bodies[i].Params[0].(map[string]string)["to"])}
+						return
+					}
+					out.balance, err = hmysolidityio.DecodeInt(data, 0)
+					if err != nil {
+						ch <- goBalance{err: err}
+						return
+					}
+				}
+				ch <- out
+			}
+		}(conn, bodiesByConn[i], idxsByConn[i])
+	}
+	// Read output
+	tokenBalances = make([]*big.Int, len(tokens))
+	for i := 0; i < len(tokens)+1; i++ {
+		var out goBalance
+		select {
+		case out = <-ch:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if out.err != nil {
+			return nil, nil, out.err
+		}
+		if out.idx == -1 {
+			balance = out.balance
+		} else {
+			tokenBalances[out.idx] = out.balance
+		}
 	}
 	return
 }
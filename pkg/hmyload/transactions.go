@@ -1,14 +1,15 @@
 package hmyload
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/cache"
 	"github.com/mjmar01/harmolytics/pkg/rpc"
 	"github.com/mjmar01/harmolytics/pkg/types"
 	"math"
 	"math/big"
-	"strings"
 )
 
 const (
@@ -18,20 +19,72 @@ const (
 	transactionHistoryMethod = "hmyv2_getTransactionsHistory"
 )
 
-// GetTransactionsByWallet returns a list of all successful Transaction for a given types.Address
+// GetTransactionsByWallet returns a list of all successful Transaction for a given types.Address on every shard.
+// Transfers to another shard appear only on their source shard, join them with their receipts using GetCrossShardTransfers
 func (l *Loader) GetTransactionsByWallet(addr types.Address) (txs []types.Transaction, err error) {
+	return l.GetTransactionsByWalletContext(context.Background(), addr)
+}
+
+// GetTransactionsByWalletContext is GetTransactionsByWallet but stops loading and returns ctx.Err() once ctx is done
+func (l *Loader) GetTransactionsByWalletContext(ctx context.Context, addr types.Address) (txs []types.Transaction, err error) {
+	txCh, errCh := l.StreamTransactionsByWallet(ctx, addr, nil)
+	for tx := range txCh {
+		txs = append(txs, tx)
+	}
+	err = <-errCh
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// StreamTransactionsByWallet loads the same transactions as GetTransactionsByWallet shard by shard in ascending order.
+// Transactions are sent as soon as their chunk is loaded and progress is called after every history page and chunk.
+// The transaction channel is closed once loading stopped, the error channel then yields the error if any.
+// Cancel ctx to stop early without draining the transaction channel
+func (l *Loader) StreamTransactionsByWallet(ctx context.Context, addr types.Address, progress func(WalletProgress)) (<-chan types.Transaction, <-chan error) {
+	txCh, errCh := make(chan types.Transaction, l.txChunkSize), make(chan error, 1)
+	go func() {
+		var p WalletProgress
+		report := func() {
+			if progress != nil {
+				progress(p)
+			}
+		}
+		var err error
+		for _, shard := range l.shardLoaders() {
+			err = shard.streamTransactionsByWallet(ctx, addr, &p, report, txCh)
+			if err != nil {
+				break
+			}
+		}
+		close(txCh)
+		if err != nil {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+	return txCh, errCh
+}
+
+func (l *Loader) streamTransactionsByWallet(ctx context.Context, addr types.Address, p *WalletProgress, report func(), txCh chan<- types.Transaction) (err error) {
 	// Get total number of transactions and prepare slice with a bit overhead
-	c, err := l.defaultConn.Call(transactionCountMethod, addr.OneAddress, rpc.AllTx)
+	c, err := l.defaultConn.CallContext(ctx, transactionCountMethod, addr.OneAddress, rpc.AllTx)
 	if err != nil {
 		return
 	}
-	txCount := int(c.(float64) * 1.01)
+	n, err := readCount(c)
+	if err != nil {
+		return
+	}
+	txCount := int(float64(n) * 1.01)
 	// Split into groups and let pages overlap a bit
-	pageSize, overlap := 50000, 50
-	// Get histories
-	uniqueHashes := map[string]bool{}
+	pageSize, overlap := l.historyPageSize, 50
+	p.Pages += (txCount + pageSize - 1) / pageSize
+	// Get histories. Hashes are kept in order of their first appearance
+	uniqueHashes, hashes := map[string]bool{}, []string{}
 	for i := 0; i < txCount; i += pageSize {
-		res, err := l.defaultConn.RawCall(transactionHistoryMethod, map[string]interface{}{
+		res, err := l.defaultConn.RawCallContext(ctx, transactionHistoryMethod, map[string]interface{}{
 			"address":   addr.OneAddress,
 			"pageIndex": i / pageSize,
 			"pageSize":  pageSize + overlap,
@@ -40,66 +93,185 @@ func (l *Loader) GetTransactionsByWallet(addr types.Address) (txs []types.Transa
 			"order":     "ASC",
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
-		// Sort hashes into a map
-		hashes, err := readTxHistory(res)
+		pageHashes, err := readTxHistory(res)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		for _, hash := range hashes {
-			uniqueHashes[hash] = true
+		for _, hash := range pageHashes {
+			if hash != "" && !uniqueHashes[hash] {
+				uniqueHashes[hash] = true
+				hashes = append(hashes, hash)
+			}
 		}
+		p.PagesFetched++
+		report()
 	}
-	// Get transactions by hash
-	hashes := make([]string, len(uniqueHashes))
-	i := 0
-	for hash := range uniqueHashes {
-		hashes[i] = hash
-		i++
-	}
-	// Split into pages to avoid node stress
-	txs = make([]types.Transaction, len(hashes))
-	uniqueMethods := map[string]bool{}
-	for i := 0; i < len(hashes); i += 5000 {
-		size := int(math.Min(float64(len(hashes)-i), 5000))
-		txsPart, err := l.GetFullTransactions(hashes[i : i+size]...)
+	p.Txs += len(hashes)
+	return l.loadChunks(ctx, hashes, func(txs []types.Transaction, cacheHits int) error {
+		for _, tx := range txs {
+			select {
+			case txCh <- tx:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		p.TxsLoaded += len(txs)
+		p.CacheHits += cacheHits
+		report()
+		return nil
+	})
+}
+
+// SyncWallet returns the transactions of a given types.Address that were added to its history since the last sync.
+// The first sync of a wallet returns its whole history. Every shard has its own sync cursor kept in the cache.
+// Like GetTransactionsByWallet it doesn't resolve transfers to other shards, see GetCrossShardTransfers
+func (l *Loader) SyncWallet(addr types.Address) (txs []types.Transaction, err error) {
+	return l.SyncWalletContext(context.Background(), addr)
+}
+
+// SyncWalletContext is SyncWallet but stops loading and returns ctx.Err() once ctx is done.
+// Cursors are only moved once the new transactions of all shards were loaded, a failed sync can simply be repeated
+func (l *Loader) SyncWalletContext(ctx context.Context, addr types.Address) (txs []types.Transaction, err error) {
+	shards := l.shardLoaders()
+	cursors := make([]*cache.WalletCursor, len(shards))
+	for i, shard := range shards {
+		var shardTxs []types.Transaction
+		shardTxs, cursors[i], err = shard.syncWallet(ctx, addr)
 		if err != nil {
 			return nil, err
 		}
-		for j, tx := range txsPart {
-			txs[i+j] = tx
-			if tx.Method.Signature != "" {
-				uniqueMethods[tx.Method.Signature] = true
-			}
+		txs = append(txs, shardTxs...)
+	}
+	for i, shard := range shards {
+		if cursors[i] != nil {
+			shard.cache.SetWalletCursor(shard.shardId, addr, *cursors[i])
 		}
 	}
-	for sig := range uniqueMethods {
-		_, ok := l.cache.GetMethod(sig)
-		if !ok {
-			m, err := l.GetMethod(sig)
-			if err != nil {
-				return nil, err
+	return
+}
+
+// syncWallet returns the transactions added since the cursor of the shard and the moved cursor, which is nil if nothing was added
+func (l *Loader) syncWallet(ctx context.Context, addr types.Address) (txs []types.Transaction, moved *cache.WalletCursor, err error) {
+	cursor, _ := l.cache.GetWalletCursor(l.shardId, addr)
+	c, err := l.defaultConn.CallContext(ctx, transactionCountMethod, addr.OneAddress, rpc.AllTx)
+	if err != nil {
+		return
+	}
+	txCount, err := readCount(c)
+	if err != nil {
+		return
+	}
+	if txCount <= cursor.TxCount {
+		return
+	}
+	// History is in ascending order so new entries start at the cursor
+	pageSize := uint64(l.historyPageSize)
+	uniqueHashes, hashes, seen := map[string]bool{}, []string{}, cursor.TxCount
+	for page := cursor.TxCount / pageSize; page*pageSize < txCount; page++ {
+		res, err := l.defaultConn.RawCallContext(ctx, transactionHistoryMethod, map[string]interface{}{
+			"address":   addr.OneAddress,
+			"pageIndex": page,
+			"pageSize":  pageSize,
+			"fullTx":    false,
+			"txType":    rpc.AllTx,
+			"order":     "ASC",
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		pageHashes, err := readTxHistory(res)
+		if err != nil {
+			return nil, nil, err
+		}
+		for j, hash := range pageHashes {
+			pos := page*pageSize + uint64(j)
+			if hash == "" || pos < cursor.TxCount {
+				continue
+			}
+			seen = pos + 1
+			if !uniqueHashes[hash] {
+				uniqueHashes[hash] = true
+				hashes = append(hashes, hash)
 			}
-			m.Signature = sig
-			l.cache.SetMethod(&m)
 		}
 	}
+	err = l.loadChunks(ctx, hashes, func(part []types.Transaction, _ int) error {
+		txs = append(txs, part...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	cursor.TxCount = seen
 	for _, tx := range txs {
+		if tx.BlockNum > cursor.LastBlock {
+			cursor.LastBlock = tx.BlockNum
+		}
+	}
+	return txs, &cursor, nil
+}
+
+// loadChunks loads full transactions with methods for the given hashes in chunks to avoid node stress.
+// Each loaded chunk is passed to the given function in order
+func (l *Loader) loadChunks(ctx context.Context, hashes []string, each func(txs []types.Transaction, cacheHits int) error) (err error) {
+	for i := 0; i < len(hashes); i += l.txChunkSize {
+		size := int(math.Min(float64(len(hashes)-i), float64(l.txChunkSize)))
+		txs, cacheHits, err := l.getFullTransactions(ctx, hashes[i:i+size])
+		if err != nil {
+			return err
+		}
+		err = l.setMethods(ctx, txs)
+		if err != nil {
+			return err
+		}
+		err = each(txs, cacheHits)
+		if err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// setMethods fills in method information, signatures missing from the cache are looked up once
+func (l *Loader) setMethods(ctx context.Context, txs []types.Transaction) (err error) {
+	for i, tx := range txs {
+		if tx.Method.Signature == "" {
+			continue
+		}
 		m, ok := l.cache.GetMethod(tx.Method.Signature)
-		if ok {
-			tx.Method = *m
+		if !ok {
+			newM, err := l.getMethod(ctx, tx.Method.Signature)
+			if err != nil {
+				return err
+			}
+			newM.Signature = tx.Method.Signature
+			l.cache.SetMethod(&newM)
+			m = &newM
 		}
+		txs[i].Method = *m
 	}
 	return
 }
 
 // GetFullTransactions returns transactions and their receipts for every given hash. Does not include method information (yet)
 func (l *Loader) GetFullTransactions(hashes ...string) (txs []types.Transaction, err error) {
+	return l.GetFullTransactionsContext(context.Background(), hashes...)
+}
+
+// GetFullTransactionsContext is GetFullTransactions but stops in-flight batches and returns ctx.Err() once ctx is done
+func (l *Loader) GetFullTransactionsContext(ctx context.Context, hashes ...string) (txs []types.Transaction, err error) {
+	txs, _, err = l.getFullTransactions(ctx, hashes)
+	return
+}
+
+// getFullTransactions additionally returns how many transactions were found in the cache
+func (l *Loader) getFullTransactions(ctx context.Context, hashes []string) (txs []types.Transaction, foundInCache int, err error) {
 	// Prepare requests
 	txs = make([]types.Transaction, len(hashes))
 	txByEthHash, txByHash := map[string]*types.Transaction{}, map[string]*types.Transaction{}
-	bodiesByConn, idx, foundInCache := make([][]rpc.Body, l.uniqueConnCount), 0, 0
+	bodiesByConn, idx := make([][]rpc.Body, l.uniqueConnCount), 0
 	for _, hash := range hashes {
 		if pTx, ok := l.cache.GetTransaction(hash); ok {
 			// Cache hit
@@ -121,8 +293,8 @@ func (l *Loader) GetFullTransactions(hashes ...string) (txs []types.Transaction,
 	// Do requests across unique nodes
 	ch := make(chan goTx, len(hashes)-foundInCache)
 	for i, conn := range l.uniqueConns {
-		go func(rpc *rpc.RPC, bodies []rpc.Body) {
-			ress, err := rpc.RawBatchCall(bodies)
+		go func(conn rpc.Client, bodies []rpc.Body) {
+			ress, err := conn.RawBatchCallContext(ctx, bodies)
 			if err != nil {
 				ch <- goTx{err: err}
 				return
@@ -139,6 +311,9 @@ func (l *Loader) GetFullTransactions(hashes ...string) (txs []types.Transaction,
 					ch <- goTx{err: err}
 					return
 				}
+				for j := range tx.Logs {
+					tx.Logs[j].BlockNum = tx.BlockNum
+				}
 				m, ok := l.cache.GetMethod(tx.Method.Signature)
 				if ok {
 					tx.Method = *m
@@ -152,9 +327,14 @@ func (l *Loader) GetFullTransactions(hashes ...string) (txs []types.Transaction,
 	}
 	// Read output
 	for i := foundInCache; i < len(hashes); i++ {
-		out := <-ch
+		var out goTx
+		select {
+		case out = <-ch:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
 		if out.err != nil {
-			return txs, out.err
+			return txs, 0, out.err
 		}
 		txByHash[out.tx.TxHash] = out.tx
 		txByEthHash[out.tx.EthTxHash] = out.tx
@@ -165,7 +345,7 @@ func (l *Loader) GetFullTransactions(hashes ...string) (txs []types.Transaction,
 		if !ok {
 			txPtr, ok = txByHash[hash]
 			if !ok {
-				return nil, errors.Errorf("Given hash (%s) was not found in list of results", hash)
+				return nil, 0, errors.Errorf("Given hash (%s) was not found in list of results", hash)
 			}
 		}
 		txs[i] = *txPtr
@@ -235,9 +415,19 @@ func readTxReceiptFromResponse(data []byte) (s int, ls []types.TransactionLog, e
 }
 
 func readTxHistory(data []byte) (txs []string, err error) {
-	// Cutoff wrapper
-	data = data[17 : len(data)-2]
-	// Split into single txs
-	txs = strings.Split(strings.ReplaceAll(string(data), "\"", ""), ",")
-	return
+	var history transactionHistoryJson
+	err = json.Unmarshal(data, &history)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return history.Transactions, nil
+}
+
+// readCount reads a transaction count result
+func readCount(c interface{}) (n uint64, err error) {
+	f, ok := c.(float64)
+	if !ok || f < 0 {
+		return 0, errors.Errorf("%v is not a transaction count", c)
+	}
+	return uint64(f), nil
 }
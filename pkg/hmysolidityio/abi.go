@@ -0,0 +1,490 @@
+package hmysolidityio
+
+import (
+	"encoding/hex"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	boolKind = iota
+	intKind
+	uintKind
+	addressKind
+	fixedBytesKind
+	bytesKind
+	stringKind
+	arrayKind
+	sliceKind
+	tupleKind
+)
+
+// abiType is a parsed Solidity type
+type abiType struct {
+	kind int
+	// Bits of integers, length of bytesN and fixed size arrays
+	size       int
+	elem       *abiType
+	components []abiType
+}
+
+var (
+	twoTo256    = new(big.Int).Lsh(big.NewInt(1), 256)
+	bigIntType  = reflect.TypeOf(new(big.Int))
+	addressType = reflect.TypeOf(types.Address{})
+	bytesType   = reflect.TypeOf([]byte{})
+)
+
+// parseType reads a canonical type like uint256, bytes32[2][] or (address,uint256[])
+func parseType(s string) (t abiType, err error) {
+	s = strings.TrimSpace(s)
+	// Array suffixes are read from the right, the last one is the outermost
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open == -1 {
+			return abiType{}, errors.Errorf("type %s has unbalanced brackets", s)
+		}
+		elem, err := parseType(s[:open])
+		if err != nil {
+			return abiType{}, err
+		}
+		if s[open+1:len(s)-1] == "" {
+			return abiType{kind: sliceKind, elem: &elem}, nil
+		}
+		size, err := strconv.Atoi(s[open+1 : len(s)-1])
+		if err != nil || size <= 0 {
+			return abiType{}, errors.Errorf("type %s has an invalid array size", s)
+		}
+		return abiType{kind: arrayKind, size: size, elem: &elem}, nil
+	}
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		parts, err := splitTypes(s[1 : len(s)-1])
+		if err != nil {
+			return abiType{}, err
+		}
+		t = abiType{kind: tupleKind, components: make([]abiType, len(parts))}
+		for i, part := range parts {
+			t.components[i], err = parseType(part)
+			if err != nil {
+				return abiType{}, err
+			}
+		}
+		return t, nil
+	}
+	switch {
+	case s == "bool":
+		return abiType{kind: boolKind}, nil
+	case s == "address":
+		return abiType{kind: addressKind}, nil
+	case s == "string":
+		return abiType{kind: stringKind}, nil
+	case s == "bytes":
+		return abiType{kind: bytesKind}, nil
+	case s == "function":
+		// An address followed by a method signature
+		return abiType{kind: fixedBytesKind, size: 24}, nil
+	case s == "int" || s == "uint":
+		s += "256"
+	}
+	for prefix, kind := range map[string]int{"uint": uintKind, "int": intKind, "bytes": fixedBytesKind} {
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(s, prefix))
+		if err != nil {
+			break
+		}
+		if kind == fixedBytesKind && size >= 1 && size <= 32 || kind != fixedBytesKind && size%8 == 0 && size >= 8 && size <= 256 {
+			return abiType{kind: kind, size: size}, nil
+		}
+	}
+	return abiType{}, errors.Errorf("unsupported type %s", s)
+}
+
+// splitTypes splits a comma separated type list, commas inside tuples are kept
+func splitTypes(s string) (parts []string, err error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 {
+			return nil, errors.Errorf("type list %s has unbalanced parentheses", s)
+		}
+	}
+	if depth != 0 {
+		return nil, errors.Errorf("type list %s has unbalanced parentheses", s)
+	}
+	return append(parts, s[start:]), nil
+}
+
+// dynamic types are encoded in the tail and referenced by an offset in the head
+func (t abiType) dynamic() bool {
+	switch t.kind {
+	case bytesKind, stringKind, sliceKind:
+		return true
+	case arrayKind:
+		return t.elem.dynamic()
+	case tupleKind:
+		for _, c := range t.components {
+			if c.dynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize is the number of bytes t takes up in the head of its tuple
+func (t abiType) headSize() int {
+	if t.dynamic() {
+		return 32
+	}
+	switch t.kind {
+	case arrayKind:
+		return t.size * t.elem.headSize()
+	case tupleKind:
+		size := 0
+		for _, c := range t.components {
+			size += c.headSize()
+		}
+		return size
+	}
+	return 32
+}
+
+// elems returns the types of the values of arrays and slices as a tuple
+func (t abiType) elems(n int) []abiType {
+	elems := make([]abiType, n)
+	for i := range elems {
+		elems[i] = *t.elem
+	}
+	return elems
+}
+
+//<editor-fold desc="Decoding">
+
+// decodeTuple decodes values of the given types from data starting at the beginning of the tuple.
+// Offsets of dynamic values are relative to the beginning of the tuple
+func decodeTuple(ts []abiType, data []byte) (values []interface{}, err error) {
+	values = make([]interface{}, len(ts))
+	pos := 0
+	for i, t := range ts {
+		start := pos
+		if t.dynamic() {
+			offset, err := readSize(data, pos)
+			if err != nil {
+				return nil, err
+			}
+			start = offset
+		}
+		if start > len(data) {
+			return nil, errors.Errorf("offset %d is out of range", start)
+		}
+		values[i], err = decodeValue(t, data[start:])
+		if err != nil {
+			return nil, err
+		}
+		pos += t.headSize()
+	}
+	return
+}
+
+// decodeValue decodes a value of type t at the beginning of data
+func decodeValue(t abiType, data []byte) (v interface{}, err error) {
+	switch t.kind {
+	case arrayKind:
+		// Every element takes at least one word, sizes of untrusted types must not exhaust memory
+		if t.size > len(data)/32 {
+			return nil, errors.Errorf("%s does not fit into %d bytes", t, len(data))
+		}
+		return decodeTuple(t.elems(t.size), data)
+	case sliceKind:
+		n, err := readSize(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		// Every element takes at least one word
+		if n > len(data)/32 {
+			return nil, errors.Errorf("array length %d is out of range", n)
+		}
+		return decodeTuple(t.elems(n), data[32:])
+	case tupleKind:
+		return decodeTuple(t.components, data)
+	case bytesKind, stringKind:
+		n, err := readSize(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < 32+n {
+			return nil, errors.Errorf("length %d is out of range", n)
+		}
+		b := make([]byte, n)
+		copy(b, data[32:32+n])
+		if t.kind == stringKind {
+			return string(b), nil
+		}
+		return b, nil
+	}
+	word, err := readWord(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case boolKind:
+		n := new(big.Int).SetBytes(word)
+		if n.BitLen() > 1 {
+			return nil, errors.Errorf("%x is not a bool", word)
+		}
+		return n.BitLen() == 1, nil
+	case addressKind:
+		return types.CheckNewAddress("0x" + hex.EncodeToString(word[12:]))
+	case fixedBytesKind:
+		b := make([]byte, t.size)
+		copy(b, word)
+		return b, nil
+	case uintKind:
+		n := new(big.Int).SetBytes(word)
+		if n.BitLen() > t.size {
+			return nil, errors.Errorf("%s exceeds uint%d", n, t.size)
+		}
+		return n, nil
+	default:
+		// Signed integers are two's complement
+		n := new(big.Int).SetBytes(word)
+		if n.Bit(255) == 1 {
+			n.Sub(n, twoTo256)
+		}
+		if !fitsInt(n, t.size) {
+			return nil, errors.Errorf("%s exceeds int%d", n, t.size)
+		}
+		return n, nil
+	}
+}
+
+func readWord(data []byte, pos int) ([]byte, error) {
+	if pos < 0 || len(data) < pos+32 {
+		return nil, errors.Errorf("input is too short for a value at byte %d", pos)
+	}
+	return data[pos : pos+32], nil
+}
+
+// readSize reads an offset or length which has to fit into the input
+func readSize(data []byte, pos int) (int, error) {
+	word, err := readWord(data, pos)
+	if err != nil {
+		return 0, err
+	}
+	n := new(big.Int).SetBytes(word)
+	if !n.IsInt64() || n.Int64() > int64(len(data)) {
+		return 0, errors.Errorf("size %s is out of range", n)
+	}
+	return int(n.Int64()), nil
+}
+
+//</editor-fold>
+
+//<editor-fold desc="Encoding">
+
+// encodeTuple encodes values as a tuple of the given types. Values are any Go values accepted by encodeValue
+func encodeTuple(ts []abiType, values []reflect.Value) (out []byte, err error) {
+	if len(ts) != len(values) {
+		return nil, errors.Errorf("got %d values for %d types", len(values), len(ts))
+	}
+	headSize := 0
+	for _, t := range ts {
+		headSize += t.headSize()
+	}
+	var head, tail []byte
+	for i, t := range ts {
+		enc, err := encodeValue(t, values[i])
+		if err != nil {
+			return nil, err
+		}
+		if t.dynamic() {
+			head = append(head, encodeSize(headSize+len(tail))...)
+			tail = append(tail, enc...)
+		} else {
+			head = append(head, enc...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+// encodeValue encodes v as type t. Integers can be *big.Int or any Go integer, bytes []byte or byte arrays,
+// addresses types.Address, arrays any slice or array and tuples []interface{} or structs with abi tags
+func encodeValue(t abiType, v reflect.Value) (out []byte, err error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr && v.Type() != bigIntType {
+		if v.IsNil() {
+			return nil, errors.Errorf("nil value for %s", t)
+		}
+		v = v.Elem()
+	}
+	switch t.kind {
+	case arrayKind, sliceKind:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, errors.Errorf("%s is not an array", v.Type())
+		}
+		if t.kind == arrayKind && v.Len() != t.size {
+			return nil, errors.Errorf("got %d values for %s", v.Len(), t)
+		}
+		values := make([]reflect.Value, v.Len())
+		for i := range values {
+			values[i] = v.Index(i)
+		}
+		out, err = encodeTuple(t.elems(len(values)), values)
+		if err != nil || t.kind == arrayKind {
+			return
+		}
+		return append(encodeSize(len(values)), out...), nil
+	case tupleKind:
+		values, err := tupleValues(v)
+		if err != nil {
+			return nil, err
+		}
+		return encodeTuple(t.components, values)
+	case bytesKind, stringKind:
+		var b []byte
+		if v.Kind() == reflect.String {
+			b = []byte(v.String())
+		} else if v.Type() == bytesType {
+			b = v.Bytes()
+		} else {
+			return nil, errors.Errorf("%s is not %s", v.Type(), t)
+		}
+		out = append(encodeSize(len(b)), b...)
+		return append(out, make([]byte, (32-len(b)%32)%32)...), nil
+	}
+	out = make([]byte, 32)
+	switch t.kind {
+	case boolKind:
+		if v.Kind() != reflect.Bool {
+			return nil, errors.Errorf("%s is not a bool", v.Type())
+		}
+		if v.Bool() {
+			out[31] = 1
+		}
+	case addressKind:
+		if v.Type() != addressType {
+			return nil, errors.Errorf("%s is not an address", v.Type())
+		}
+		addr, err := hex.DecodeString(strings.TrimPrefix(v.Interface().(types.Address).HexAddress, "0x"))
+		if err != nil || len(addr) != 20 {
+			return nil, errors.Errorf("%v is not a valid address", v.Interface())
+		}
+		copy(out[12:], addr)
+	case fixedBytesKind:
+		if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() != reflect.Uint8 {
+			return nil, errors.Errorf("%s is not bytes", v.Type())
+		}
+		if v.Len() > t.size {
+			return nil, errors.Errorf("%d bytes exceed bytes%d", v.Len(), t.size)
+		}
+		reflect.Copy(reflect.ValueOf(out), v)
+	default:
+		n, err := bigValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if t.kind == uintKind && (n.Sign() < 0 || n.BitLen() > t.size) || t.kind == intKind && !fitsInt(n, t.size) {
+			return nil, errors.Errorf("%s exceeds %s", n, t)
+		}
+		if n.Sign() < 0 {
+			// Two's complement
+			n = new(big.Int).Add(n, twoTo256)
+		}
+		n.FillBytes(out)
+	}
+	return out, nil
+}
+
+func encodeSize(n int) []byte {
+	return big.NewInt(int64(n)).FillBytes(make([]byte, 32))
+}
+
+// bigValue converts *big.Int and Go integers
+func bigValue(v reflect.Value) (n *big.Int, err error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), nil
+	}
+	if v.Type() != bigIntType || v.IsNil() {
+		return nil, errors.Errorf("%s is not an integer", v.Type())
+	}
+	return v.Interface().(*big.Int), nil
+}
+
+// tupleValues returns the components of a tuple given as slice or struct with abi tags
+func tupleValues(v reflect.Value) (values []reflect.Value, err error) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		values = make([]reflect.Value, v.Len())
+		for i := range values {
+			values[i] = v.Index(i)
+		}
+	case reflect.Struct:
+		fields, err := taggedFields(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			values = append(values, v.Field(f.index))
+		}
+	default:
+		return nil, errors.Errorf("%s is not a tuple", v.Type())
+	}
+	return
+}
+
+//</editor-fold>
+
+func fitsInt(n *big.Int, bits int) bool {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+// String returns the canonical type
+func (t abiType) String() string {
+	switch t.kind {
+	case boolKind:
+		return "bool"
+	case intKind:
+		return "int" + strconv.Itoa(t.size)
+	case uintKind:
+		return "uint" + strconv.Itoa(t.size)
+	case addressKind:
+		return "address"
+	case fixedBytesKind:
+		return "bytes" + strconv.Itoa(t.size)
+	case bytesKind:
+		return "bytes"
+	case stringKind:
+		return "string"
+	case arrayKind:
+		return t.elem.String() + "[" + strconv.Itoa(t.size) + "]"
+	case sliceKind:
+		return t.elem.String() + "[]"
+	}
+	ts := make([]string, len(t.components))
+	for i, c := range t.components {
+		ts[i] = c.String()
+	}
+	return "(" + strings.Join(ts, ",") + ")"
+}
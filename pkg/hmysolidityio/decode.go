@@ -7,6 +7,7 @@ import (
 	"github.com/mjmar01/harmolytics/pkg/types"
 	"math/big"
 	"strings"
+	"unicode/utf8"
 )
 
 // DecodeInt returns the contained uint256 as a *big.Int given the entire data input and position of the value.
@@ -17,6 +18,9 @@ func DecodeInt(data string, intPosition int) (n *big.Int, err error) {
 	if err != nil {
 		return
 	}
+	if len(data) < intPosition+64 {
+		return nil, errors.Errorf("input is too short for a value at position %d", intPosition/64)
+	}
 	bytes, err := hex.DecodeString(data[intPosition : intPosition+64])
 	if err != nil {
 		return
@@ -35,21 +39,29 @@ func DecodeArray(data string, arrayPosition int) (arr [][]byte, err error) {
 	if err != nil {
 		return
 	}
-	offset, err := hexutil.DecodeUint64("0x" + strings.TrimLeft(data[arrayPosition:arrayPosition+64], "0"))
+	if len(data) < arrayPosition+64 {
+		return nil, errors.Errorf("input is too short for an array at position %d", arrayPosition/64)
+	}
+	offset, err := decodeUint(data[arrayPosition : arrayPosition+64])
 	if err != nil {
-		return nil, errors.Wrap(err, 0)
+		return
+	}
+	if offset > uint64(len(data)-64)/2 {
+		return nil, errors.Errorf("array offset %d is out of range", offset)
 	}
 	data = data[offset*2:]
-	arrLen64, err := hexutil.DecodeUint64("0x" + strings.TrimLeft(data[:64], "0"))
+	arrLen, err := decodeUint(data[:64])
 	if err != nil {
-		return nil, errors.Wrap(err, 0)
+		return
+	}
+	if arrLen > uint64(len(data)-64)/64 {
+		return nil, errors.Errorf("array length %d is out of range", arrLen)
 	}
-	arrLen := int(arrLen64)
-	for i := 0; i < arrLen; i++ {
+	for i := uint64(0); i < arrLen; i++ {
 		data = data[64:]
 		data, err := hex.DecodeString(data[:64])
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, 0)
 		}
 		arr = append(arr, data)
 	}
@@ -64,6 +76,9 @@ func DecodeAddress(data string, addressPosition int) (a types.Address, err error
 	if err != nil {
 		return
 	}
+	if len(data) < addressPosition+64 {
+		return types.Address{}, errors.Errorf("input is too short for an address at position %d", addressPosition/64)
+	}
 	a, err = types.CheckNewAddress("0x" + data[addressPosition+24:addressPosition+64])
 	return
 }
@@ -76,14 +91,23 @@ func DecodeString(data string, stringPosition int) (s string, err error) {
 	if err != nil {
 		return
 	}
-	offset, err := hexutil.DecodeUint64("0x" + strings.TrimLeft(data[stringPosition:stringPosition+64], "0"))
+	if len(data) < stringPosition+64 {
+		return "", errors.Errorf("input is too short for a string at position %d", stringPosition/64)
+	}
+	offset, err := decodeUint(data[stringPosition : stringPosition+64])
 	if err != nil {
-		return "", errors.Wrap(err, 0)
+		return
+	}
+	if offset > uint64(len(data)-64)/2 {
+		return "", errors.Errorf("string offset %d is out of range", offset)
 	}
 	data = data[offset*2:]
-	strLen, err := hexutil.DecodeUint64("0x" + strings.TrimLeft(data[:64], "0"))
+	strLen, err := decodeUint(data[:64])
 	if err != nil {
-		return "", errors.Wrap(err, 0)
+		return
+	}
+	if strLen > uint64(len(data)-64)/2 {
+		return "", errors.Errorf("string length %d is out of range", strLen)
 	}
 	bytes, err := hex.DecodeString(data[64 : 64+strLen*2])
 	if err != nil {
@@ -92,3 +116,136 @@ func DecodeString(data string, stringPosition int) (s string, err error) {
 	s = string(bytes)
 	return
 }
+
+// DecodeBytes32String returns the string contained in a bytes32 value padded with zeros on the right,
+// as returned by some older contracts instead of a string
+func DecodeBytes32String(data string, position int) (s string, err error) {
+	position *= 64
+	data, err = cleanInput(data)
+	if err != nil {
+		return
+	}
+	if len(data) < position+64 {
+		return "", errors.Errorf("input is too short for a bytes32 at position %d", position/64)
+	}
+	bytes, err := hex.DecodeString(data[position : position+64])
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	bytes = []byte(strings.TrimRight(string(bytes), "\x00"))
+	if !utf8.Valid(bytes) {
+		return "", errors.Errorf("bytes32 value is not a string")
+	}
+	return string(bytes), nil
+}
+
+// decodeUint reads a 32 byte word which has to fit into an uint64
+func decodeUint(word string) (n uint64, err error) {
+	word = strings.TrimLeft(word, "0")
+	if word == "" {
+		return 0, nil
+	}
+	n, err = hexutil.DecodeUint64("0x" + word)
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+	return
+}
+
+// DecodeBool returns the contained bool given the entire data input and position of the value.
+// The position usually corresponds to the parameter position of the function call.
+func DecodeBool(data string, boolPosition int) (b bool, err error) {
+	v, err := decodePosition(data, boolPosition, abiType{kind: boolKind})
+	if err != nil {
+		return
+	}
+	return v.(bool), nil
+}
+
+// DecodeSignedInt returns the contained int256 as a *big.Int given the entire data input and position of the value.
+// The value is read as two's complement. The position usually corresponds to the parameter position of the function call.
+func DecodeSignedInt(data string, intPosition int) (n *big.Int, err error) {
+	v, err := decodePosition(data, intPosition, abiType{kind: intKind, size: 256})
+	if err != nil {
+		return
+	}
+	return v.(*big.Int), nil
+}
+
+// DecodeFixedBytes returns the contained bytesN value of the given size given the entire data input and position of the value.
+// The position usually corresponds to the parameter position of the function call.
+func DecodeFixedBytes(data string, bytesPosition int, size int) (b []byte, err error) {
+	if size < 1 || size > 32 {
+		return nil, errors.Errorf("bytes%d is not a valid type", size)
+	}
+	v, err := decodePosition(data, bytesPosition, abiType{kind: fixedBytesKind, size: size})
+	if err != nil {
+		return
+	}
+	return v.([]byte), nil
+}
+
+// DecodeBytes returns the contained dynamic bytes given the entire data input and position of the value.
+// The position usually corresponds to the parameter position of the function call.
+func DecodeBytes(data string, bytesPosition int) (b []byte, err error) {
+	v, err := decodePosition(data, bytesPosition, abiType{kind: bytesKind})
+	if err != nil {
+		return
+	}
+	return v.([]byte), nil
+}
+
+// decodePosition decodes a value of type t which is referenced by the word at position
+func decodePosition(data string, position int, t abiType) (v interface{}, err error) {
+	data, err = cleanInput(data)
+	if err != nil {
+		return
+	}
+	b, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	start := position * 32
+	if t.dynamic() {
+		start, err = readSize(b, start)
+		if err != nil {
+			return
+		}
+	}
+	if start < 0 || start > len(b) {
+		return nil, errors.Errorf("input is too short for a value at position %d", position)
+	}
+	return decodeValue(t, b[start:])
+}
+
+// Decode returns all values of data given its comma separated Solidity types, e.g. (address,uint256[],bytes).
+// Surrounding parentheses are optional, a single tuple parameter is written ((address,uint256)).
+// Values are bool, *big.Int for integers, types.Address, []byte for bytes and bytesN, string
+// and []interface{} for arrays and tuples. Data can start with 0x and a method signature
+func Decode(typeList string, data string) (values []interface{}, err error) {
+	typeList = strings.TrimSpace(typeList)
+	if strings.HasPrefix(typeList, "(") && strings.HasSuffix(typeList, ")") {
+		// Only strip parentheses enclosing the whole list, not ones of (a,b),(c,d)
+		if parts, err := splitTypes(typeList[1 : len(typeList)-1]); err == nil {
+			typeList = strings.Join(parts, ",")
+		}
+	}
+	t, err := parseType("(" + typeList + ")")
+	if err != nil {
+		return
+	}
+	data, err = cleanInput(data)
+	if err != nil {
+		return
+	}
+	b, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return decodeTuple(t.components, b)
+}
+
+// DecodeMethodInput returns all parameters of a method call given the method and the call input
+func DecodeMethodInput(m types.Method, input string) (values []interface{}, err error) {
+	return Decode("("+strings.Join(m.Parameters, ",")+")", input)
+}
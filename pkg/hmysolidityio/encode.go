@@ -3,155 +3,107 @@ package hmysolidityio
 import (
 	"encoding/hex"
 	"github.com/go-errors/errors"
-	"github.com/mjmar01/harmolytics/pkg/types"
-	"math"
-	"math/big"
-	"sort"
+	"reflect"
 )
 
-type Encoder struct {
-	in       []interface{}
-	out      string
-	offset   int
-	carry    int
-	prefixes []string
-	suffixes map[int]string
-}
-
+// EncodeAll encodes the given values as the parameters of a call. Solidity types are derived from the Go types:
+// *big.Int and Go integers are uint256 or int256 if negative, types.Address is address, bool is bool, string is string,
+// []byte is bytes, [N]byte is bytesN, other arrays are T[N], other slices including []interface{} are T[]
+// and structs with abi tags are tuples, see EncodeStruct. Elements of an array must share their type
 func EncodeAll(in ...interface{}) (out string, err error) {
-	e := &Encoder{
-		in:       in,
-		out:      "",
-		offset:   0,
-		carry:    0,
-		prefixes: make([]string, len(in)),
-		suffixes: map[int]string{},
+	ts := make([]abiType, len(in))
+	values := make([]reflect.Value, len(in))
+	for i, v := range in {
+		values[i] = reflect.ValueOf(v)
+		ts[i], err = goType(values[i])
+		if err != nil {
+			return
+		}
 	}
-	err = checkInput(in)
+	b, err := encodeTuple(ts, values)
 	if err != nil {
 		return
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	// First level
-	next := map[int][]interface{}{}
-	for i, value := range in {
-		switch value.(type) {
-		case *big.Int:
-			e.prefixes[i] = encodeInt(value.(*big.Int))
-		case types.Address:
-			e.prefixes[i] = encodeAddress(value.(types.Address))
-		case string:
-			suffix := encodeString(value.(string))
-			offset, prefix := e.getOffset(len(suffix) / 64)
-			e.prefixes[i] = prefix
-			e.suffixes[offset] = suffix
-		case []interface{}:
-			l := len(value.([]interface{}))
-			offset, prefix := e.getOffset(l + 1)
-			e.prefixes[i] = prefix
-			next[offset] = value.([]interface{})
-		}
-	}
-
-	// Next levels
-	for offset, n := range next {
-		e.recurseEncode(offset, n)
-	}
-
-	// Build out
-	for _, prefix := range e.prefixes {
-		e.out += prefix
-	}
-	var keys []int
-	for k := range e.suffixes {
-		keys = append(keys, k)
+// goType derives the Solidity type of a Go value
+func goType(v reflect.Value) (t abiType, err error) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr && v.Type() != bigIntType) {
+		v = v.Elem()
 	}
-	sort.Ints(keys)
-	for _, key := range keys {
-		e.out += e.suffixes[key]
+	if !v.IsValid() {
+		return abiType{}, errors.Errorf("nil is not encodable")
 	}
-	return e.out, nil
-}
-
-func (e *Encoder) recurseEncode(offset int, in []interface{}) {
-	n := new(big.Int)
-	n.SetInt64(int64(len(in)))
-	e.suffixes[offset] = encodeInt(n)
-	next := map[int][]interface{}{}
-	for _, value := range in {
-		switch value.(type) {
-		case *big.Int:
-			e.suffixes[offset] += encodeInt(value.(*big.Int))
-		case types.Address:
-			e.suffixes[offset] += encodeAddress(value.(types.Address))
-		case string:
-			suffix := encodeString(value.(string))
-			subOffset, prefix := e.getOffset(len(suffix) / 64)
-			e.suffixes[offset] += prefix
-			e.suffixes[subOffset] = suffix
-		case []interface{}:
-			l := len(value.([]interface{}))
-			subOffset, prefix := e.getOffset(l + 1)
-			e.suffixes[offset] += prefix
-			next[subOffset] = value.([]interface{})
+	switch v.Type() {
+	case bigIntType:
+		if v.IsNil() {
+			return abiType{}, errors.Errorf("nil is not encodable")
 		}
+		return intType(v), nil
+	case addressType:
+		return abiType{kind: addressKind}, nil
+	case bytesType:
+		return abiType{kind: bytesKind}, nil
 	}
-	for offset, n := range next {
-		e.recurseEncode(offset, n)
-	}
-}
-
-func checkInput(in []interface{}) (err error) {
-	for _, i := range in {
-		switch i.(type) {
-		case *big.Int:
-			if len(i.(*big.Int).Bytes()) > 32 {
-				return errors.Errorf("Input integer exceeds maximum size of 256bit")
+	switch v.Kind() {
+	case reflect.Bool:
+		return abiType{kind: boolKind}, nil
+	case reflect.String:
+		return abiType{kind: stringKind}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return intType(v), nil
+	case reflect.Struct:
+		return structType(v.Type())
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() < 1 || v.Len() > 32 {
+				return abiType{}, errors.Errorf("%s is not a bytesN", v.Type())
 			}
-		case string:
-			continue
-		case types.Address:
-			continue
-		case []interface{}:
-			err = checkInput(i.([]interface{}))
-			if err != nil {
-				return
-			}
-		default:
-			return errors.Errorf("Unsupported input type")
+			return abiType{kind: fixedBytesKind, size: v.Len()}, nil
+		}
+		elem, err := elemType(v)
+		if err != nil {
+			return abiType{}, err
 		}
+		return abiType{kind: arrayKind, size: v.Len(), elem: &elem}, nil
+	case reflect.Slice:
+		elem, err := elemType(v)
+		if err != nil {
+			return abiType{}, err
+		}
+		return abiType{kind: sliceKind, elem: &elem}, nil
 	}
-	return nil
+	return abiType{}, errors.Errorf("%s is not encodable", v.Type())
 }
 
-func (e *Encoder) getOffset(l int) (offset int, s string) {
-	offset = len(e.in)*32 + e.carry
-	e.carry += l * 32
-	b := new(big.Int)
-	b.SetInt64(int64(offset))
-	s = hex.EncodeToString(b.FillBytes(make([]byte, 32)))
-	return
-}
-
-func encodeString(in string) (s string) {
-	s = encodeInt(big.NewInt(int64(len(in))))
-	size := int(math.Ceil(float64(len(in)) / 32))
-	bytes := make([]byte, size*32)
-	for i, c := range in {
-		bytes[i] = byte(c)
+// intType returns int256 for negative integers and uint256 otherwise
+func intType(v reflect.Value) abiType {
+	if n, err := bigValue(v); err == nil && n.Sign() < 0 {
+		return abiType{kind: intKind, size: 256}
 	}
-	s += hex.EncodeToString(bytes)
-	return
+	return abiType{kind: uintKind, size: 256}
 }
 
-func encodeInt(n *big.Int) (s string) {
-	bytes := make([]byte, 32)
-	bytes = n.FillBytes(bytes)
-	s = hex.EncodeToString(bytes)
-	return
-}
-
-func encodeAddress(a types.Address) (s string) {
-	s = "000000000000000000000000" + a.HexAddress[2:]
+// elemType returns the common type of the elements of an array. Integers are int256 if any of them is negative
+func elemType(v reflect.Value) (t abiType, err error) {
+	if v.Len() == 0 {
+		// Empty arrays are encoded the same for every type
+		return abiType{kind: uintKind, size: 256}, nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		elem, err := goType(v.Index(i))
+		if err != nil {
+			return abiType{}, err
+		}
+		switch {
+		case i == 0 || elem.kind == intKind && t.kind == uintKind:
+			t = elem
+		case elem.kind == uintKind && t.kind == intKind:
+		case elem.String() != t.String():
+			return abiType{}, errors.Errorf("array mixes %s and %s", t, elem)
+		}
+	}
 	return
 }
@@ -0,0 +1,217 @@
+package hmysolidityio
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	FourByteUrl = "https://www.4byte.directory/api/v1/signatures/?hex_signature=0x"
+)
+
+// SignatureResolver looks up the name and parameters of a method by its 4 byte signature in hex without 0x.
+// ok is false if the signature is unknown to the resolver
+type SignatureResolver interface {
+	ResolveMethod(ctx context.Context, sig string) (m types.Method, ok bool, err error)
+}
+
+// FourByteResolver asks a 4byte.directory compatible API for every signature
+type FourByteResolver struct {
+	// Url is prefixed to the signature
+	Url    string
+	Client *http.Client
+}
+
+// DatabaseResolver looks up signatures in memory, see BundledResolver, NewDatabaseResolver and NewAbiResolver
+type DatabaseResolver struct {
+	methods map[string]string
+}
+
+// ChainResolver asks each resolver in turn until one knows the signature.
+// Errors are only returned if no resolver knows the signature
+type ChainResolver []SignatureResolver
+
+//go:embed signatures.txt
+var bundledSignatures string
+var bundledResolver = mustParseBundled()
+
+// NewFourByteResolver creates a FourByteResolver for the given API, FourByteUrl if url is empty
+func NewFourByteResolver(url string) *FourByteResolver {
+	if url == "" {
+		url = FourByteUrl
+	}
+	return &FourByteResolver{Url: url, Client: http.DefaultClient}
+}
+
+func (r *FourByteResolver) ResolveMethod(ctx context.Context, sig string) (m types.Method, ok bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.Url+sig, nil)
+	if err != nil {
+		return types.Method{}, false, errors.Wrap(err, 0)
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return types.Method{}, false, errors.Wrap(err, 0)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return types.Method{}, false, errors.Wrap(err, 0)
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return types.Method{}, false, errors.Wrap(err, 0)
+	}
+	// Rate limits and server errors must not be mistaken for unknown signatures
+	if resp.StatusCode != http.StatusOK {
+		return types.Method{}, false, errors.Errorf("%s answered %s", r.Url+sig, resp.Status)
+	}
+	var data struct {
+		Results []struct {
+			TextSignature string `json:"text_signature"`
+			ID            int    `json:"id"`
+		} `json:"results"`
+	}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return types.Method{}, false, errors.Wrap(err, 0)
+	}
+	if len(data.Results) == 0 {
+		return types.Method{}, false, nil
+	}
+	// Sort to get most likely match
+	sort.Slice(data.Results, func(i, j int) bool {
+		return data.Results[i].ID < data.Results[j].ID
+	})
+	return methodFromText(sig, data.Results[0].TextSignature), true, nil
+}
+
+// BundledResolver returns a resolver for common token, NFT, DEX and farm methods which works offline
+func BundledResolver() *DatabaseResolver {
+	return bundledResolver
+}
+
+// mustParseBundled parses the bundled signatures.txt. It is part of the source, so a broken file is a bug
+func mustParseBundled() *DatabaseResolver {
+	db, err := NewDatabaseResolver(strings.NewReader(bundledSignatures))
+	if err != nil {
+		panic("hmysolidityio: bundled signatures.txt is invalid: " + err.Error())
+	}
+	return db
+}
+
+// NewDatabaseResolver reads one method per line as hex signature and text signature separated by a space,
+// e.g. "a9059cbb transfer(address,uint256)". Empty lines and lines starting with # are skipped
+func NewDatabaseResolver(r io.Reader) (db *DatabaseResolver, err error) {
+	db = &DatabaseResolver{methods: map[string]string{}}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 || len(fields[0]) != 8 || !strings.Contains(fields[1], "(") {
+			return nil, errors.Errorf("line %d is not a signature: %s", line, text)
+		}
+		db.methods[strings.ToLower(fields[0])] = fields[1]
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+// NewAbiResolver reads the functions of the given contract ABI JSON files
+func NewAbiResolver(paths ...string) (db *DatabaseResolver, err error) {
+	db = &DatabaseResolver{methods: map[string]string{}}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		var abi []abiEntryJson
+		err = json.Unmarshal(data, &abi)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		for _, entry := range abi {
+			if entry.Type != "function" {
+				continue
+			}
+			text := entry.Name + abiTupleType(entry.Inputs)
+			db.methods[hex.EncodeToString(crypto.Keccak256([]byte(text))[:4])] = text
+		}
+	}
+	return
+}
+
+func (db *DatabaseResolver) ResolveMethod(_ context.Context, sig string) (m types.Method, ok bool, err error) {
+	text, ok := db.methods[strings.ToLower(sig)]
+	if !ok {
+		return types.Method{}, false, nil
+	}
+	return methodFromText(sig, text), true, nil
+}
+
+func (c ChainResolver) ResolveMethod(ctx context.Context, sig string) (m types.Method, ok bool, err error) {
+	var firstErr error
+	for _, r := range c {
+		m, ok, err = r.ResolveMethod(ctx, sig)
+		if ok {
+			return m, true, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return types.Method{}, false, firstErr
+}
+
+type abiEntryJson struct {
+	Type   string         `json:"type"`
+	Name   string         `json:"name"`
+	Inputs []abiInputJson `json:"inputs"`
+}
+
+type abiInputJson struct {
+	Type       string         `json:"type"`
+	Components []abiInputJson `json:"components"`
+}
+
+// abiTupleType returns the canonical type of the given inputs, tuples are written as their components
+func abiTupleType(inputs []abiInputJson) string {
+	ts := make([]string, len(inputs))
+	for i, input := range inputs {
+		ts[i] = input.Type
+		if strings.HasPrefix(input.Type, "tuple") {
+			ts[i] = abiTupleType(input.Components) + strings.TrimPrefix(input.Type, "tuple")
+		}
+	}
+	return "(" + strings.Join(ts, ",") + ")"
+}
+
+// methodFromText splits a text signature like transfer(address,uint256) into name and parameters
+func methodFromText(sig, text string) types.Method {
+	split := strings.IndexRune(text, '(')
+	inner := strings.TrimSuffix(text[split+1:], ")")
+	// Keep tuple parameters in one piece
+	params, err := splitTypes(inner)
+	if err != nil {
+		params = strings.Split(inner, ",")
+	}
+	return types.Method{
+		Signature:  sig,
+		Name:       text[:split],
+		Parameters: params,
+	}
+}
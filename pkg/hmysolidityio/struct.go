@@ -0,0 +1,199 @@
+package hmysolidityio
+
+import (
+	"encoding/hex"
+	"github.com/go-errors/errors"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// field is a struct field with an abi tag
+type field struct {
+	index int
+	t     abiType
+}
+
+// EncodeStruct encodes the fields of a struct tagged with their Solidity type, e.g. `abi:"uint256"`.
+// Untagged fields are skipped. Nested structs are tagged "tuple", "tuple[]" or "tuple[N]".
+// Integers can be *big.Int or any Go integer, bytes []byte or byte arrays
+func EncodeStruct(v interface{}) (out string, err error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return "", errors.Errorf("%T is not a struct", v)
+	}
+	t, err := structType(rv.Type())
+	if err != nil {
+		return
+	}
+	b, err := encodeValue(t, rv)
+	if err != nil {
+		return
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// DecodeStruct decodes data into the tagged fields of the struct v points to, see EncodeStruct.
+// Data can start with 0x and a method signature
+func DecodeStruct(data string, v interface{}) (err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("%T is not a pointer to a struct", v)
+	}
+	t, err := structType(rv.Elem().Type())
+	if err != nil {
+		return
+	}
+	data, err = cleanInput(data)
+	if err != nil {
+		return
+	}
+	b, err := hex.DecodeString(data)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	values, err := decodeTuple(t.components, b)
+	if err != nil {
+		return
+	}
+	return assign(rv.Elem(), values)
+}
+
+// structType returns the tuple type of a struct with abi tags
+func structType(st reflect.Type) (t abiType, err error) {
+	fields, err := taggedFields(st)
+	if err != nil {
+		return
+	}
+	t = abiType{kind: tupleKind, components: make([]abiType, len(fields))}
+	for i, f := range fields {
+		t.components[i] = f.t
+	}
+	return
+}
+
+func taggedFields(st reflect.Type) (fields []field, err error) {
+	for i := 0; i < st.NumField(); i++ {
+		tag, ok := st.Field(i).Tag.Lookup("abi")
+		if !ok || tag == "-" {
+			continue
+		}
+		var t abiType
+		if strings.HasPrefix(tag, "tuple") {
+			t, err = tupleFieldType(st.Field(i).Type, strings.TrimPrefix(tag, "tuple"))
+		} else {
+			t, err = parseType(tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{index: i, t: t})
+	}
+	return
+}
+
+// tupleFieldType builds the type of a field tagged tuple with array suffixes from its struct type
+func tupleFieldType(ft reflect.Type, suffix string) (t abiType, err error) {
+	if suffix == "" {
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			return abiType{}, errors.Errorf("field of type %s can't be a tuple", ft)
+		}
+		return structType(ft)
+	}
+	open := strings.LastIndex(suffix, "[")
+	if open == -1 || !strings.HasSuffix(suffix, "]") || (ft.Kind() != reflect.Slice && ft.Kind() != reflect.Array) {
+		return abiType{}, errors.Errorf("field of type %s can't be a tuple%s", ft, suffix)
+	}
+	elem, err := tupleFieldType(ft.Elem(), suffix[:open])
+	if err != nil {
+		return
+	}
+	// Let parseType validate the array size
+	t, err = parseType("bool" + suffix[open:])
+	if err != nil {
+		return
+	}
+	t.elem = &elem
+	return
+}
+
+// assign sets dst to a decoded value converting it to the type of dst
+func assign(dst reflect.Value, v interface{}) (err error) {
+	src := reflect.ValueOf(v)
+	switch {
+	case dst.Kind() == reflect.Interface:
+		dst.Set(src)
+		return
+	case dst.Kind() == reflect.Ptr && dst.Type() != bigIntType:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), v)
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+		return
+	}
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(*big.Int)
+		if !ok || !n.IsInt64() || dst.OverflowInt(n.Int64()) {
+			return errors.Errorf("%v does not fit into %s", v, dst.Type())
+		}
+		dst.SetInt(n.Int64())
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(*big.Int)
+		if !ok || !n.IsUint64() || dst.OverflowUint(n.Uint64()) {
+			return errors.Errorf("%v does not fit into %s", v, dst.Type())
+		}
+		dst.SetUint(n.Uint64())
+		return
+	case reflect.Array:
+		if b, ok := v.([]byte); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			if len(b) != dst.Len() {
+				return errors.Errorf("%d bytes don't fit into %s", len(b), dst.Type())
+			}
+			reflect.Copy(dst, src)
+			return
+		}
+	}
+	values, ok := v.([]interface{})
+	if !ok {
+		return errors.Errorf("%T can't be assigned to %s", v, dst.Type())
+	}
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.MakeSlice(dst.Type(), len(values), len(values)))
+	case reflect.Array:
+		if dst.Len() != len(values) {
+			return errors.Errorf("%d values don't fit into %s", len(values), dst.Type())
+		}
+	case reflect.Struct:
+		fields, err := taggedFields(dst.Type())
+		if err != nil {
+			return err
+		}
+		if len(fields) != len(values) {
+			return errors.Errorf("%d values don't fit into %s", len(values), dst.Type())
+		}
+		for i, f := range fields {
+			err = assign(dst.Field(f.index), values[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("%T can't be assigned to %s", v, dst.Type())
+	}
+	for i, value := range values {
+		err = assign(dst.Index(i), value)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
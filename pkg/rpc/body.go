@@ -1,5 +1,7 @@
 package rpc
 
+import "sync/atomic"
+
 // NewBody prepares a body with correct syntax and incremental IDs
 func (r *RPC) NewBody(method string, params ...interface{}) (b Body) {
 	if params == nil {
@@ -7,10 +9,9 @@ func (r *RPC) NewBody(method string, params ...interface{}) (b Body) {
 	}
 	b = Body{
 		RpcVersion: "2.0",
-		Id:         r.queryId,
+		Id:         int(atomic.AddInt64(&r.queryId, 1)),
 		Method:     method,
 		Params:     params,
 	}
-	r.queryId++
 	return
 }
@@ -0,0 +1,75 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+)
+
+// RawCaller is the part of a Client sending calls
+type RawCaller interface {
+	RawCallContext(ctx context.Context, method string, params ...interface{}) ([]byte, error)
+	RawBatchCallContext(ctx context.Context, bodies []Body) ([][]byte, error)
+}
+
+// Calls derives the remaining call methods of a Client from the RawCaller it was created with.
+// Clients wrapping or replacing RPC embed it and only implement RawCallContext and RawBatchCallContext
+type Calls struct {
+	raw RawCaller
+}
+
+// NewCalls creates Calls sending everything through raw
+func NewCalls(raw RawCaller) Calls {
+	return Calls{raw: raw}
+}
+
+// Call executes an RPC and returns the result as a generic interface
+func (c Calls) Call(method string, params ...interface{}) (result interface{}, err error) {
+	return c.CallContext(context.Background(), method, params...)
+}
+
+// CallContext is Call but stops waiting and returns ctx.Err() once ctx is done
+func (c Calls) CallContext(ctx context.Context, method string, params ...interface{}) (result interface{}, err error) {
+	raw, err := c.raw.RawCallContext(ctx, method, params...)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
+
+// BatchCall executes one RPC per given Body and returns the result as a slice of generic interfaces
+func (c Calls) BatchCall(bodies []Body) (results []interface{}, err error) {
+	return c.BatchCallContext(context.Background(), bodies)
+}
+
+// BatchCallContext is BatchCall but stops waiting and returns ctx.Err() once ctx is done
+func (c Calls) BatchCallContext(ctx context.Context, bodies []Body) (results []interface{}, err error) {
+	raws, err := c.raw.RawBatchCallContext(ctx, bodies)
+	if raws == nil {
+		return nil, err
+	}
+	results = make([]interface{}, len(bodies))
+	for i, raw := range raws {
+		if raw == nil {
+			continue
+		}
+		if jsonErr := json.Unmarshal(raw, &results[i]); jsonErr != nil {
+			return nil, errors.Wrap(jsonErr, 0)
+		}
+	}
+	return
+}
+
+// RawCall executes an RPC and returns the raw JSON result
+func (c Calls) RawCall(method string, params ...interface{}) (result []byte, err error) {
+	return c.raw.RawCallContext(context.Background(), method, params...)
+}
+
+// RawBatchCall executes one RPC per given Body and returns the result as a slice of raw JSON results
+func (c Calls) RawBatchCall(bodies []Body) (results [][]byte, err error) {
+	return c.raw.RawBatchCallContext(context.Background(), bodies)
+}
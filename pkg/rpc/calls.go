@@ -1,91 +1,100 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/go-errors/errors"
-	"strings"
-	"time"
 )
 
-// Call executes an RPC and returns the result as a generic interface
+// Call executes an RPC and returns the result as a generic interface.
+// If the node replies with an error object it is returned as *Error
 func (r *RPC) Call(method string, params ...interface{}) (result interface{}, err error) {
-	body := r.NewBody(method, params...)
-	err = r.ws.WriteJSON(body)
+	return r.CallContext(context.Background(), method, params...)
+}
+
+// CallContext is Call but stops waiting and returns ctx.Err() once ctx is done
+func (r *RPC) CallContext(ctx context.Context, method string, params ...interface{}) (result interface{}, err error) {
+	rpls, err := r.send(ctx, []Body{r.NewBody(method, params...)})
 	if err != nil {
-		return nil, errors.Wrap(err, 0)
+		return nil, err
+	}
+	if rpls[0].Error != nil {
+		return nil, errors.Wrap(rpls[0].Error, 0)
 	}
-	r.ws.SetReadDeadline(time.Now().Add(r.timeout))
-	var rst rpcReplyG
-	err = r.ws.ReadJSON(&rst)
+	err = json.Unmarshal(rpls[0].Result, &result)
 	if err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
-	result = rst.Result
 	return
 }
 
-// BatchCall executes one RPC per given Body and returns the result as a slice of generic interfaces
+// BatchCall executes one RPC per given Body and returns the result as a slice of generic interfaces.
+// If any node reply is an error object a BatchError is returned alongside the results of the successful calls
 func (r *RPC) BatchCall(bodies []Body) (results []interface{}, err error) {
+	return r.BatchCallContext(context.Background(), bodies)
+}
+
+// BatchCallContext is BatchCall but stops waiting and returns ctx.Err() once ctx is done
+func (r *RPC) BatchCallContext(ctx context.Context, bodies []Body) (results []interface{}, err error) {
+	rpls, err := r.send(ctx, bodies)
+	if err != nil {
+		return nil, err
+	}
 	results = make([]interface{}, len(bodies))
-	idx := make(map[int]int, len(bodies))
-	for i, body := range bodies {
-		idx[body.Id] = i
-		err = r.ws.WriteJSON(body)
-		if err != nil {
-			return nil, errors.Wrap(err, 0)
+	for i, rpl := range rpls {
+		if rpl.Error != nil {
+			continue
 		}
-	}
-	for i := 0; i < len(bodies); i++ {
-		r.ws.SetReadDeadline(time.Now().Add(r.timeout))
-		var rst rpcReplyG
-		err = r.ws.ReadJSON(&rst)
+		err = json.Unmarshal(rpl.Result, &results[i])
 		if err != nil {
 			return nil, errors.Wrap(err, 0)
 		}
-		results[idx[rst.Id]] = rst.Result
+	}
+	if err = batchError(rpls); err != nil {
+		return results, errors.Wrap(err, 0)
 	}
 	return
 }
 
-// RawCall executes an RPC and returns the raw JSON result
+// RawCall executes an RPC and returns the raw JSON result.
+// If the node replies with an error object it is returned as *Error
 func (r *RPC) RawCall(method string, params ...interface{}) (result []byte, err error) {
-	body := r.NewBody(method, params...)
-	err = r.ws.WriteJSON(body)
+	return r.RawCallContext(context.Background(), method, params...)
+}
+
+// RawCallContext is RawCall but stops waiting and returns ctx.Err() once ctx is done
+func (r *RPC) RawCallContext(ctx context.Context, method string, params ...interface{}) (result []byte, err error) {
+	rpls, err := r.send(ctx, []Body{r.NewBody(method, params...)})
 	if err != nil {
-		return nil, errors.Wrap(err, 0)
+		return nil, err
 	}
-	r.ws.SetReadDeadline(time.Now().Add(r.timeout))
-	_, rs, err := r.ws.ReadMessage()
-	if err != nil {
-		return result, errors.Wrap(err, 0)
+	if rpls[0].Error != nil {
+		return nil, errors.Wrap(rpls[0].Error, 0)
 	}
-	result = []byte(strings.TrimSuffix(strings.SplitAfterN(string(rs), ":", 4)[3], "}\n"))
+	result = rpls[0].Result
 	return
 }
 
-// RawBatchCall executes one RPC per given Body and returns the result as a slice of raw JSON results
+// RawBatchCall executes one RPC per given Body and returns the result as a slice of raw JSON results.
+// If any node reply is an error object a BatchError is returned alongside the results of the successful calls
 func (r *RPC) RawBatchCall(bodies []Body) (results [][]byte, err error) {
+	return r.RawBatchCallContext(context.Background(), bodies)
+}
+
+// RawBatchCallContext is RawBatchCall but stops waiting and returns ctx.Err() once ctx is done
+func (r *RPC) RawBatchCallContext(ctx context.Context, bodies []Body) (results [][]byte, err error) {
+	rpls, err := r.send(ctx, bodies)
+	if err != nil {
+		return nil, err
+	}
 	results = make([][]byte, len(bodies))
-	idx := make(map[int]int, len(bodies))
-	for i, body := range bodies {
-		idx[body.Id] = i
-		err = r.ws.WriteJSON(body)
-		if err != nil {
-			return nil, errors.Wrap(err, 0)
+	for i, rpl := range rpls {
+		if rpl.Error == nil {
+			results[i] = rpl.Result
 		}
 	}
-	for i := 0; i < len(bodies); i++ {
-		r.ws.SetReadDeadline(time.Now().Add(r.timeout))
-		var rst rpcReplyG
-		_, rs, err := r.ws.ReadMessage()
-		if err != nil {
-			return nil, errors.Wrap(err, 0)
-		}
-		err = json.Unmarshal(rs, &rst)
-		if err != nil {
-			return nil, errors.Wrap(err, 0)
-		}
-		results[idx[rst.Id]] = []byte(strings.TrimSuffix(strings.SplitAfterN(string(rs), ":", 4)[3], "}\n"))
+	if err = batchError(rpls); err != nil {
+		return results, errors.Wrap(err, 0)
 	}
 	return
 }
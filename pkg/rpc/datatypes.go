@@ -1,7 +1,11 @@
 package rpc
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/gorilla/websocket"
+	"net/http"
+	"sync"
 	"time"
 )
 
@@ -12,31 +16,130 @@ const (
 	ReceivedTx = "RECEIVED"
 )
 
-// RPC struct to interact with RPC endpoints
+// Client is implemented by everything that executes RPCs against a harmony node.
+// RPC talks to real nodes, MemoryClient serves prepared results offline
+type Client interface {
+	NewBody(method string, params ...interface{}) Body
+	Call(method string, params ...interface{}) (interface{}, error)
+	CallContext(ctx context.Context, method string, params ...interface{}) (interface{}, error)
+	BatchCall(bodies []Body) ([]interface{}, error)
+	BatchCallContext(ctx context.Context, bodies []Body) ([]interface{}, error)
+	RawCall(method string, params ...interface{}) ([]byte, error)
+	RawCallContext(ctx context.Context, method string, params ...interface{}) ([]byte, error)
+	RawBatchCall(bodies []Body) ([][]byte, error)
+	RawBatchCallContext(ctx context.Context, bodies []Body) ([][]byte, error)
+	Close()
+	PeerId() string
+}
+
+// Subscriber is implemented by clients able to receive notifications. RPC does so over websockets
+type Subscriber interface {
+	Subscribe(ctx context.Context, params ...interface{}) (*Subscription, error)
+}
+
+// RPC struct to interact with RPC endpoints.
+// A single RPC can be shared across go routines, replies are routed to their callers by ID.
 type RPC struct {
-	PeerId  string
+	peerId  string
+	url     string
 	timeout time.Duration
+	retry   *RetryPolicy
 	ws      *websocket.Conn
-	queryId int
+	// Used instead of the websocket for http(s) URLs
+	client    *http.Client
+	batchSize int
+	queryId   int64
+	closed    int32
+	drops     int
+	// Writing to the websocket
+	writeMutex sync.Mutex
+	// Routing replies to waiting callers
+	wireId       int64
+	pending      map[int64]pendingCall
+	pendingMutex sync.Mutex
+	done         chan struct{}
+	doneErr      error
+	// Routing notifications to subscriptions by their ID on the node
+	subs     map[string]*Subscription
+	subMutex sync.Mutex
 }
 
 // Opts contains optional parameters for the NewRPC function
 type Opts struct {
 	Timeout time.Duration
+	Retry   *RetryPolicy
+	// BatchSize is the maximum number of calls sent in a single HTTP request. Defaults to 1000
+	BatchSize int
 }
 
 func defaults(in *Opts) (out *Opts) {
-	if in == nil {
-		out = new(Opts)
-	} else {
-		out = in
+	// Work on a copy, NewRPCs shares opts across go routines
+	out = new(Opts)
+	if in != nil {
+		*out = *in
 	}
 	if out.Timeout == 0 {
 		out.Timeout = time.Minute * 2
 	}
+	if out.BatchSize == 0 {
+		out.BatchSize = 1000
+	}
+	if out.Retry == nil {
+		out.Retry = &RetryPolicy{MaxAttempts: 1}
+	}
 	return
 }
 
+// RetryPolicy describes how failed calls and dropped connections are retried.
+// Dropped websockets are redialed and calls that were still waiting for a reply are sent again
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one. 1 disables retrying
+	MaxAttempts int
+	// Backoff is the wait before the first retry, it doubles with every further attempt
+	Backoff time.Duration
+	// MaxBackoff caps the wait between two attempts. Defaults to one minute
+	MaxBackoff time.Duration
+	// Retryable decides which errors are worth another attempt. Defaults to DefaultRetryable
+	Retryable func(err error) bool
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	ParseErrorCode     = -32700
+	InvalidRequestCode = -32600
+	MethodNotFoundCode = -32601
+	InvalidParamsCode  = -32602
+	InternalErrorCode  = -32603
+	ServerErrorCode    = -32000
+	LimitExceededCode  = -32005
+)
+
+// Error is the error object a node replies with instead of a result
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// BatchError is returned by batch calls if at least one call was answered with an Error.
+// It holds one entry per given Body, entries of successful calls are nil.
+type BatchError []*Error
+
+// Subscription receives the notifications of an eth_subscribe call. After reconnects it subscribes again by itself
+type Subscription struct {
+	r    *RPC
+	args []interface{}
+	id   string
+	// Notifications are queued so a slow reader never blocks the websocket
+	queue  []json.RawMessage
+	mutex  sync.Mutex
+	signal chan struct{}
+	ch     chan json.RawMessage
+	err    error
+	done   chan struct{}
+	ended  int32
+}
+
 // Body represents an RPC calls body input
 type Body struct {
 	RpcVersion string        `json:"jsonrpc"`
@@ -48,10 +151,33 @@ type Body struct {
 //</editor-fold>
 
 //<editor-fold desc="Internal types">
-type rpcReplyG struct {
-	RpcVersion string      `json:"jsonrpc"`
-	Id         int         `json:"id"`
-	Result     interface{} `json:"result"`
+type rpcReply struct {
+	RpcVersion string          `json:"jsonrpc"`
+	Id         int64           `json:"id"`
+	Result     json.RawMessage `json:"result"`
+	Error      *Error          `json:"error"`
+}
+
+type pendingCall struct {
+	ch   chan rpcReply
+	body wireBody
+	// Set for eth_subscribe calls. The subscription is registered as soon as the reply is read
+	sub *Subscription
+}
+
+type rpcNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+type wireBody struct {
+	RpcVersion string        `json:"jsonrpc"`
+	Id         int64         `json:"id"`
+	Method     string        `json:"method"`
+	Params     []interface{} `json:"params"`
 }
 
 // goFunc returns
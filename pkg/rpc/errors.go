@@ -0,0 +1,40 @@
+package rpc
+
+import "fmt"
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func (e BatchError) Error() string {
+	failed, first := 0, -1
+	for i, err := range e {
+		if err != nil {
+			failed++
+			if first == -1 {
+				first = i
+			}
+		}
+	}
+	if failed == 0 {
+		return "no rpc errors"
+	}
+	return fmt.Sprintf("%d of %d calls failed, first at position %d: %s", failed, len(e), first, e[first].Error())
+}
+
+// batchError collects the errors of all replies. Returns nil if every call succeeded
+func batchError(rpls []rpcReply) error {
+	var e BatchError
+	for i, rpl := range rpls {
+		if rpl.Error != nil {
+			if e == nil {
+				e = make(BatchError, len(rpls))
+			}
+			e[i] = rpl.Error
+		}
+	}
+	if e == nil {
+		return nil
+	}
+	return e
+}
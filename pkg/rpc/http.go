@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+// postRoundTrip sends the bodies at the given indices as JSON-RPC batch arrays of at most batchSize calls.
+// Returns the indices that should be tried again
+func (r *RPC) postRoundTrip(ctx context.Context, bodies []Body, todo []int, rpls []rpcReply) (retry []int, err error) {
+	for start := 0; start < len(todo); start += r.batchSize {
+		end := start + r.batchSize
+		if end > len(todo) {
+			end = len(todo)
+		}
+		r.pendingMutex.Lock()
+		err = r.doneErr
+		r.pendingMutex.Unlock()
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		// Prepare batch with unique IDs
+		batch, idxById := make([]wireBody, end-start), make(map[int64]int, end-start)
+		for i, idx := range todo[start:end] {
+			batch[i] = wireBody{
+				RpcVersion: bodies[idx].RpcVersion,
+				Id:         atomic.AddInt64(&r.wireId, 1),
+				Method:     bodies[idx].Method,
+				Params:     bodies[idx].Params,
+			}
+			idxById[batch[i].Id] = idx
+		}
+		var batchRpls []rpcReply
+		batchRpls, err = r.post(ctx, batch)
+		if err != nil {
+			return append(retry, todo[start:]...), err
+		}
+		// Sort replies back to their bodies
+		for _, rpl := range batchRpls {
+			idx, ok := idxById[rpl.Id]
+			if !ok {
+				continue
+			}
+			delete(idxById, rpl.Id)
+			rpls[idx] = rpl
+			if rpl.Error != nil && r.retry.retryable(rpl.Error) {
+				retry = append(retry, idx)
+			}
+		}
+		if len(idxById) > 0 {
+			for _, idx := range idxById {
+				retry = append(retry, idx)
+			}
+			return append(retry, todo[end:]...), errors.Errorf("node did not reply to %d of %d calls", len(idxById), len(batch))
+		}
+	}
+	return
+}
+
+func (r *RPC) post(ctx context.Context, batch []wireBody) (rpls []rpcReply, err error) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	data, err = ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to post batch. Received status: %s", rsp.Status)
+	}
+	err = json.Unmarshal(data, &rpls)
+	if err != nil {
+		// Requests failing as a whole are answered with a single error object
+		var rpl rpcReply
+		if json.Unmarshal(data, &rpl) == nil && rpl.Error != nil {
+			return nil, errors.Wrap(rpl.Error, 0)
+		}
+		return nil, errors.Wrap(err, 0)
+	}
+	return
+}
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"sync"
+	"sync/atomic"
+)
+
+// MemoryClient is a Client serving results that were set beforehand, keyed by method and params.
+// Calls without a matching result are answered with a MethodNotFoundCode Error
+type MemoryClient struct {
+	Calls
+	peerId  string
+	queryId int64
+	results map[string]memoryResult
+	mutex   sync.RWMutex
+}
+
+type memoryResult struct {
+	result json.RawMessage
+	err    *Error
+}
+
+// NewMemoryClient creates an empty MemoryClient posing as the given peer
+func NewMemoryClient(peerId string) *MemoryClient {
+	c := &MemoryClient{
+		peerId:  peerId,
+		results: map[string]memoryResult{},
+	}
+	c.Calls = NewCalls(c)
+	return c
+}
+
+// Set stores the result for a call of method with params. The result is stored as JSON
+func (c *MemoryClient) Set(result interface{}, method string, params ...interface{}) (err error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return c.SetRaw(data, method, params...)
+}
+
+// SetRaw stores the raw JSON result for a call of method with params
+func (c *MemoryClient) SetRaw(result []byte, method string, params ...interface{}) (err error) {
+	key, err := callKey(method, params)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	c.results[key] = memoryResult{result: result}
+	c.mutex.Unlock()
+	return
+}
+
+// SetError makes calls of method with params fail with the given Error
+func (c *MemoryClient) SetError(e *Error, method string, params ...interface{}) (err error) {
+	key, err := callKey(method, params)
+	if err != nil {
+		return
+	}
+	c.mutex.Lock()
+	c.results[key] = memoryResult{err: e}
+	c.mutex.Unlock()
+	return
+}
+
+// NewBody prepares a body with correct syntax and incremental IDs
+func (c *MemoryClient) NewBody(method string, params ...interface{}) (b Body) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	b = Body{
+		RpcVersion: "2.0",
+		Id:         int(atomic.AddInt64(&c.queryId, 1)),
+		Method:     method,
+		Params:     params,
+	}
+	return
+}
+
+// RawCallContext returns the stored raw JSON result or ctx.Err() if ctx is already done
+func (c *MemoryClient) RawCallContext(ctx context.Context, method string, params ...interface{}) (result []byte, err error) {
+	ress, err := c.RawBatchCallContext(ctx, []Body{c.NewBody(method, params...)})
+	if err != nil {
+		var batchErr BatchError
+		if errors.As(err, &batchErr) {
+			return nil, errors.Wrap(batchErr[0], 0)
+		}
+		return nil, err
+	}
+	return ress[0], nil
+}
+
+// RawBatchCallContext returns the stored raw JSON results or ctx.Err() if ctx is already done
+func (c *MemoryClient) RawBatchCallContext(ctx context.Context, bodies []Body) (results [][]byte, err error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	results = make([][]byte, len(bodies))
+	rpls := make([]rpcReply, len(bodies))
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for i, body := range bodies {
+		key, err := callKey(body.Method, body.Params)
+		if err != nil {
+			return nil, err
+		}
+		res, ok := c.results[key]
+		if !ok {
+			res.err = &Error{Code: MethodNotFoundCode, Message: "no result stored for " + key}
+		}
+		results[i], rpls[i].Error = res.result, res.err
+	}
+	if err = batchError(rpls); err != nil {
+		return results, errors.Wrap(err, 0)
+	}
+	return
+}
+
+// Close does nothing, a MemoryClient holds no connections
+func (c *MemoryClient) Close() {}
+
+// PeerId returns the peer ID given to NewMemoryClient
+func (c *MemoryClient) PeerId() string {
+	return c.peerId
+}
+
+// callKey identifies a call by its method and params
+func callKey(method string, params []interface{}) (key string, err error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return method + string(data), nil
+}
@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"github.com/gorilla/websocket"
+	"sync/atomic"
+	"time"
+)
+
+// listen reads every incoming message of the websocket and hands it to the caller waiting for its ID.
+// It runs until the websocket fails and either reconnects or stops all callers
+func (r *RPC) listen(ws *websocket.Conn) {
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			if r.reconnect(err) {
+				return
+			}
+			r.fail(err)
+			return
+		}
+		var rpl rpcReply
+		err = json.Unmarshal(msg, &rpl)
+		if err != nil {
+			// Not a reply anyone could be waiting for
+			continue
+		}
+		r.drops = 0
+		if rpl.Id == 0 {
+			// Wire IDs start at 1, messages without one are notifications
+			var n rpcNotification
+			if json.Unmarshal(msg, &n) == nil && n.Method == notificationMethod {
+				r.notify(n)
+			}
+			continue
+		}
+		r.pendingMutex.Lock()
+		call, ok := r.pending[rpl.Id]
+		delete(r.pending, rpl.Id)
+		r.pendingMutex.Unlock()
+		if ok {
+			if call.sub != nil {
+				r.register(call.sub, rpl)
+			}
+			call.ch <- rpl
+		}
+	}
+}
+
+// fail stops every current and future caller with the given error
+func (r *RPC) fail(err error) {
+	r.pendingMutex.Lock()
+	if r.doneErr != nil {
+		r.pendingMutex.Unlock()
+		return
+	}
+	r.doneErr = err
+	r.pending = map[int64]pendingCall{}
+	r.pendingMutex.Unlock()
+	close(r.done)
+	r.endSubscriptions(err)
+}
+
+func (r *RPC) isDone() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send writes all bodies and waits for their replies which are returned in the same order.
+// Calls answered with a retryable error or not answered in time are sent again as the retry policy allows.
+// Once ctx is done waiting stops and ctx.Err() is returned
+func (r *RPC) send(ctx context.Context, bodies []Body) (rpls []rpcReply, err error) {
+	rpls = make([]rpcReply, len(bodies))
+	todo := make([]int, len(bodies))
+	for i := range todo {
+		todo[i] = i
+	}
+	for attempt := 1; ; attempt++ {
+		var retry []int
+		retry, err = r.roundTrip(ctx, bodies, todo, rpls)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err == nil && len(retry) == 0 {
+			return
+		}
+		if err == nil {
+			err = rpls[retry[0]].Error
+		}
+		if r.isDone() || !r.retry.shouldRetry(attempt, err) {
+			if _, ok := err.(*Error); ok {
+				// Error objects are handed to the caller per reply
+				return rpls, nil
+			}
+			return nil, err
+		}
+		r.retry.wait(ctx, attempt)
+		todo = retry
+	}
+}
+
+// roundTrip sends the bodies at the given indices once and writes their replies into rpls.
+// Returns the indices that should be tried again.
+// Bodies are sent with connection unique IDs so callers can't collide with each other
+func (r *RPC) roundTrip(ctx context.Context, bodies []Body, todo []int, rpls []rpcReply) (retry []int, err error) {
+	if r.client != nil {
+		return r.postRoundTrip(ctx, bodies, todo, rpls)
+	}
+	chs, ids := make([]chan rpcReply, len(todo)), make([]int64, len(todo))
+	defer r.forget(ids)
+	for i, idx := range todo {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		ids[i] = atomic.AddInt64(&r.wireId, 1)
+		chs[i] = make(chan rpcReply, 1)
+		body := wireBody{
+			RpcVersion: bodies[idx].RpcVersion,
+			Id:         ids[i],
+			Method:     bodies[idx].Method,
+			Params:     bodies[idx].Params,
+		}
+		r.pendingMutex.Lock()
+		if r.doneErr != nil {
+			r.pendingMutex.Unlock()
+			return nil, errors.Wrap(r.doneErr, 0)
+		}
+		r.pending[ids[i]] = pendingCall{ch: chs[i], body: body}
+		r.pendingMutex.Unlock()
+		r.writeMutex.Lock()
+		err = r.ws.WriteJSON(body)
+		if err != nil && r.retry.MaxAttempts > 1 {
+			// Let the listener reconnect, the body is sent again from there
+			r.ws.Close()
+			err = nil
+		}
+		r.writeMutex.Unlock()
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+	}
+	// Every reply restarts the timeout, same as a read deadline would
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	for i, ch := range chs {
+		select {
+		case rpls[todo[i]] = <-ch:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(r.timeout)
+			if rpls[todo[i]].Error != nil && r.retry.retryable(rpls[todo[i]].Error) {
+				retry = append(retry, todo[i])
+			}
+		case <-timer.C:
+			return append(retry, todo[i:]...), errors.Errorf("timed out after %s waiting for reply to %s", r.timeout, bodies[todo[i]].Method)
+		case <-r.done:
+			return nil, errors.Wrap(r.doneErr, 0)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return
+}
+
+// forget removes IDs which are no longer waited for
+func (r *RPC) forget(ids []int64) {
+	r.pendingMutex.Lock()
+	for _, id := range ids {
+		delete(r.pending, id)
+	}
+	r.pendingMutex.Unlock()
+}
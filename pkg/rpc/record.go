@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Recorder is a Client capturing every call of the wrapped Client and its raw reply.
+// Saved fixtures can be served offline using NewReplayClient
+type Recorder struct {
+	Calls
+	client Client
+	path   string
+	calls  map[string]fixtureCall
+	mutex  sync.Mutex
+}
+
+type fixture struct {
+	PeerId string        `json:"peerId"`
+	Calls  []fixtureCall `json:"calls"`
+}
+
+type fixtureCall struct {
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// NewRecorder wraps c and records its calls. Save writes them into a fixture file at path
+func NewRecorder(c Client, path string) *Recorder {
+	r := &Recorder{
+		client: c,
+		path:   path,
+		calls:  map[string]fixtureCall{},
+	}
+	r.Calls = NewCalls(r)
+	return r
+}
+
+// NewReplayClient creates a MemoryClient serving every call recorded in the fixture file at path
+func NewReplayClient(path string) (c *MemoryClient, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	var f fixture
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	c = NewMemoryClient(f.PeerId)
+	for _, call := range f.Calls {
+		if call.Error != nil {
+			err = c.SetError(call.Error, call.Method, call.Params...)
+		} else {
+			err = c.SetRaw(call.Result, call.Method, call.Params...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// RawCallContext forwards the call to the wrapped Client and records it
+func (r *Recorder) RawCallContext(ctx context.Context, method string, params ...interface{}) (result []byte, err error) {
+	result, err = r.client.RawCallContext(ctx, method, params...)
+	var rpcErr *Error
+	if err == nil || errors.As(err, &rpcErr) {
+		r.record(fixtureCall{Method: method, Params: params, Result: result, Error: rpcErr})
+	}
+	return
+}
+
+// RawBatchCallContext forwards the calls to the wrapped Client and records them
+func (r *Recorder) RawBatchCallContext(ctx context.Context, bodies []Body) (results [][]byte, err error) {
+	results, err = r.client.RawBatchCallContext(ctx, bodies)
+	var batchErr BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return
+	}
+	for i, body := range bodies {
+		call := fixtureCall{Method: body.Method, Params: body.Params, Result: results[i]}
+		if batchErr != nil {
+			call.Error = batchErr[i]
+		}
+		r.record(call)
+	}
+	return
+}
+
+// NewBody prepares a body using the wrapped Client
+func (r *Recorder) NewBody(method string, params ...interface{}) Body {
+	return r.client.NewBody(method, params...)
+}
+
+// PeerId returns the peer ID of the wrapped Client
+func (r *Recorder) PeerId() string {
+	return r.client.PeerId()
+}
+
+// Close closes the wrapped Client. Call Save first to keep the recorded calls
+func (r *Recorder) Close() {
+	r.client.Close()
+}
+
+// Save writes all calls recorded so far to the fixture file, sorted to keep fixtures diffable
+func (r *Recorder) Save() (err error) {
+	r.mutex.Lock()
+	keys := make([]string, 0, len(r.calls))
+	for key := range r.calls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	f := fixture{PeerId: r.PeerId(), Calls: make([]fixtureCall, len(keys))}
+	for i, key := range keys {
+		f.Calls[i] = r.calls[key]
+	}
+	r.mutex.Unlock()
+	// One call per line. Results must stay byte for byte as received so no indenting
+	var buff bytes.Buffer
+	peerId, _ := json.Marshal(f.PeerId)
+	buff.WriteString(`{"peerId":` + string(peerId) + `,"calls":[`)
+	for i, call := range f.Calls {
+		data, err := json.Marshal(call)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if i > 0 {
+			buff.WriteByte(',')
+		}
+		buff.WriteString("\n")
+		buff.Write(data)
+	}
+	buff.WriteString("\n]}\n")
+	err = os.MkdirAll(filepath.Dir(r.path), 0750)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	err = ioutil.WriteFile(r.path, buff.Bytes(), 0644)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return
+}
+
+func (r *Recorder) record(call fixtureCall) {
+	key, err := callKey(call.Method, call.Params)
+	if err != nil {
+		return
+	}
+	r.mutex.Lock()
+	r.calls[key] = call
+	r.mutex.Unlock()
+}
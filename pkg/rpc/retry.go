@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"context"
+	"github.com/go-errors/errors"
+	"github.com/gorilla/websocket"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRetryable retries every connection level problem and rate limited calls.
+// Any other error object received from a node is considered final
+func DefaultRetryable(err error) bool {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code == LimitExceededCode
+	}
+	return true
+}
+
+// shouldRetry reports whether another attempt should follow the given failed attempt
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	return attempt < p.MaxAttempts && p.retryable(err)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return DefaultRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// wait sleeps for the backoff following the given failed attempt or until ctx is done
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) {
+	maxBackoff := p.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = time.Minute
+	}
+	backoff := p.Backoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// reconnect redials a dropped websocket and sends every call still waiting for a reply again.
+// Drops without any reply in between count as consecutive attempts.
+// Returns false if the RPC was closed or the policy gives up, in which case nothing was changed
+func (r *RPC) reconnect(cause error) bool {
+	for r.drops++; r.retry.shouldRetry(r.drops, cause); r.drops++ {
+		if atomic.LoadInt32(&r.closed) == 1 {
+			return false
+		}
+		r.retry.wait(context.Background(), r.drops)
+		var ws *websocket.Conn
+		ws, cause = dial(r.url)
+		if cause != nil {
+			continue
+		}
+		r.writeMutex.Lock()
+		if atomic.LoadInt32(&r.closed) == 1 {
+			r.writeMutex.Unlock()
+			ws.Close()
+			return false
+		}
+		r.ws = ws
+		r.pendingMutex.Lock()
+		replay := make([]wireBody, 0, len(r.pending))
+		for _, call := range r.pending {
+			replay = append(replay, call.body)
+		}
+		r.pendingMutex.Unlock()
+		for _, body := range replay {
+			if ws.WriteJSON(body) != nil {
+				// The new listener will notice and reconnect again
+				ws.Close()
+				break
+			}
+		}
+		r.writeMutex.Unlock()
+		go r.listen(ws)
+		go r.resubscribe()
+		return true
+	}
+	return false
+}
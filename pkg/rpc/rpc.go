@@ -1,10 +1,13 @@
-// Package rpc handles communication with harmony nodes over the RPC protocol using websockets
+// Package rpc handles communication with harmony nodes over the RPC protocol using websockets or HTTP
 package rpc
 
 import (
+	"context"
 	"github.com/go-errors/errors"
 	"github.com/gorilla/websocket"
 	"net/http"
+	"strings"
+	"sync/atomic"
 )
 
 const (
@@ -15,23 +18,42 @@ const (
 func NewRPC(url string, opts *Opts) (r *RPC, err error) {
 	opts = defaults(opts)
 	r = new(RPC)
-	var rsp *http.Response
-	r.ws, rsp, err = websocket.DefaultDialer.Dial(url, nil)
-	if err != nil {
-		if rsp.StatusCode == 502 {
-			return nil, errors.Errorf("failed to open websocket due to temporary connection issues. This shouldn't last longer than ~5min")
-		}
-		if rsp.Status != "" {
-			return nil, errors.Errorf("failed to open websocket. Received status: %s", rsp.Status)
+	r.url = url
+	r.timeout = opts.Timeout
+	r.retry = opts.Retry
+	r.pending = map[int64]pendingCall{}
+	r.subs = map[string]*Subscription{}
+	r.done = make(chan struct{})
+
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		r.client = &http.Client{Timeout: opts.Timeout}
+		r.batchSize = opts.BatchSize
+	} else {
+		for attempt := 1; ; attempt++ {
+			r.ws, err = dial(url)
+			if err == nil {
+				break
+			}
+			if !r.retry.shouldRetry(attempt, err) {
+				return nil, err
+			}
+			r.retry.wait(context.Background(), attempt)
 		}
-		return nil, errors.Wrap(err, 0)
+		go r.listen(r.ws)
 	}
 
-	r.queryId = 1
-	r.timeout = opts.Timeout
-
 	metaData, err := r.Call(NodeMetadataMethod)
-	r.PeerId = metaData.(map[string]interface{})["peerid"].(string)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	m, _ := metaData.(map[string]interface{})
+	peerId, ok := m["peerid"].(string)
+	if !ok {
+		r.Close()
+		return nil, errors.Errorf("node metadata did not contain a peer id: %v", metaData)
+	}
+	r.peerId = peerId
 	return
 }
 
@@ -57,6 +79,35 @@ func NewRPCs(url string, count int, opts *Opts) (rs []*RPC, err error) {
 	return
 }
 
+// PeerId returns the peer ID of the node this RPC is connected to
+func (r *RPC) PeerId() string {
+	return r.peerId
+}
+
+// Close closes the websocket or idle HTTP connections. Calls still waiting for replies return with an error
 func (r *RPC) Close() {
+	atomic.StoreInt32(&r.closed, 1)
+	if r.client != nil {
+		r.client.CloseIdleConnections()
+		r.fail(errors.Errorf("rpc was closed"))
+		return
+	}
+	r.writeMutex.Lock()
 	r.ws.Close()
+	r.writeMutex.Unlock()
+}
+
+func dial(url string) (ws *websocket.Conn, err error) {
+	var rsp *http.Response
+	ws, rsp, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		if rsp == nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if rsp.StatusCode == http.StatusBadGateway {
+			return nil, errors.Errorf("failed to open websocket due to temporary connection issues. This shouldn't last longer than ~5min")
+		}
+		return nil, errors.Errorf("failed to open websocket. Received status: %s", rsp.Status)
+	}
+	return
 }
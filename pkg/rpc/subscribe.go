@@ -0,0 +1,211 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-errors/errors"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	subscribeMethod    = "eth_subscribe"
+	unsubscribeMethod  = "eth_unsubscribe"
+	notificationMethod = "eth_subscription"
+)
+
+// Subscribe calls eth_subscribe with the given params, e.g. "newHeads" or "logs" and a filter.
+// Subscriptions need a websocket and stay active until Unsubscribe is called or the RPC fails
+func (r *RPC) Subscribe(ctx context.Context, params ...interface{}) (s *Subscription, err error) {
+	if r.client != nil {
+		return nil, errors.Errorf("subscriptions are not supported over HTTP")
+	}
+	s = &Subscription{
+		r:      r,
+		args:   params,
+		signal: make(chan struct{}, 1),
+		ch:     make(chan json.RawMessage),
+		done:   make(chan struct{}),
+	}
+	err = r.subscribe(ctx, s)
+	if err != nil {
+		// The node might have answered after all
+		s.Unsubscribe()
+		return nil, err
+	}
+	go s.forward()
+	return
+}
+
+// Notifications returns the channel of notification results. It is closed once the subscription ended
+func (s *Subscription) Notifications() <-chan json.RawMessage {
+	return s.ch
+}
+
+// Err returns why the subscription ended. It is nil while the subscription is active or after Unsubscribe
+func (s *Subscription) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Unsubscribe ends the subscription and tells the node to stop sending notifications
+func (s *Subscription) Unsubscribe() {
+	s.mutex.Lock()
+	id := s.id
+	s.mutex.Unlock()
+	if !s.end(nil) {
+		return
+	}
+	s.r.subMutex.Lock()
+	delete(s.r.subs, id)
+	s.r.subMutex.Unlock()
+	if id != "" && !s.r.isDone() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.r.timeout)
+		defer cancel()
+		s.r.CallContext(ctx, unsubscribeMethod, id)
+	}
+}
+
+// subscribe sends the eth_subscribe call of s and waits for its reply.
+// The listener registers s before reading the next message, so no notification is missed
+func (r *RPC) subscribe(ctx context.Context, s *Subscription) (err error) {
+	id, ch := atomic.AddInt64(&r.wireId, 1), make(chan rpcReply, 1)
+	defer r.forget([]int64{id})
+	body := wireBody{
+		RpcVersion: "2.0",
+		Id:         id,
+		Method:     subscribeMethod,
+		Params:     s.args,
+	}
+	r.pendingMutex.Lock()
+	if r.doneErr != nil {
+		r.pendingMutex.Unlock()
+		return errors.Wrap(r.doneErr, 0)
+	}
+	r.pending[id] = pendingCall{ch: ch, body: body, sub: s}
+	r.pendingMutex.Unlock()
+	r.writeMutex.Lock()
+	err = r.ws.WriteJSON(body)
+	if err != nil && r.retry.MaxAttempts > 1 {
+		// Let the listener reconnect, the body is sent again from there
+		r.ws.Close()
+		err = nil
+	}
+	r.writeMutex.Unlock()
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case rpl := <-ch:
+		if rpl.Error != nil {
+			return errors.Wrap(rpl.Error, 0)
+		}
+		return
+	case <-timer.C:
+		return errors.Errorf("timed out after %s waiting for reply to %s", r.timeout, subscribeMethod)
+	case <-r.done:
+		return errors.Wrap(r.doneErr, 0)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// register routes notifications for the ID in the reply to s
+func (r *RPC) register(s *Subscription, rpl rpcReply) {
+	var id string
+	if rpl.Error != nil || json.Unmarshal(rpl.Result, &id) != nil {
+		return
+	}
+	if atomic.LoadInt32(&s.ended) == 1 {
+		return
+	}
+	s.mutex.Lock()
+	s.id = id
+	s.mutex.Unlock()
+	r.subMutex.Lock()
+	r.subs[id] = s
+	r.subMutex.Unlock()
+}
+
+// notify queues a notification for its subscription. Notifications of unknown subscriptions are dropped
+func (r *RPC) notify(n rpcNotification) {
+	r.subMutex.Lock()
+	s, ok := r.subs[n.Params.Subscription]
+	r.subMutex.Unlock()
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	s.queue = append(s.queue, n.Params.Result)
+	s.mutex.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// resubscribe subscribes everything again after a reconnect. Old IDs are not valid on the new connection
+func (r *RPC) resubscribe() {
+	r.subMutex.Lock()
+	subs := make([]*Subscription, 0, len(r.subs))
+	for _, s := range r.subs {
+		subs = append(subs, s)
+	}
+	r.subs = map[string]*Subscription{}
+	r.subMutex.Unlock()
+	for _, s := range subs {
+		err := r.subscribe(context.Background(), s)
+		if err != nil {
+			s.end(err)
+		}
+	}
+}
+
+// endSubscriptions ends every subscription with the given error
+func (r *RPC) endSubscriptions(err error) {
+	r.subMutex.Lock()
+	subs := r.subs
+	r.subs = map[string]*Subscription{}
+	r.subMutex.Unlock()
+	for _, s := range subs {
+		s.end(err)
+	}
+}
+
+// end stops forwarding. Returns false if s already ended
+func (s *Subscription) end(err error) bool {
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return false
+	}
+	s.mutex.Lock()
+	s.err = err
+	s.mutex.Unlock()
+	close(s.done)
+	return true
+}
+
+// forward hands queued notifications to the reader until the subscription ended
+func (s *Subscription) forward() {
+	defer close(s.ch)
+	for {
+		s.mutex.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mutex.Unlock()
+		for _, n := range queue {
+			select {
+			case s.ch <- n:
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.signal:
+		case <-s.done:
+			return
+		}
+	}
+}
@@ -32,8 +32,50 @@ type Method struct {
 	Parameters []string
 }
 
+// Call contains a method call decoded with the ABI of the called contract
+type Call struct {
+	TxHash    string
+	Contract  Address
+	Method    Method
+	Arguments []Argument
+}
+
+// Event contains an event log decoded with the ABI of the event, arguments are in the order of the event definition
+type Event struct {
+	TxHash    string
+	LogIndex  int
+	Address   Address
+	Name      string
+	Arguments []Argument
+}
+
+// Argument contains a named and typed value. Integers are *big.Int, addresses Address, bytes and bytesN []byte.
+// Arrays are []interface{} of their element values and tuples []Argument of their components.
+// Indexed event arguments of dynamic types only contain the 32 byte hash of the value as []byte
+type Argument struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
 //</editor-fold
 
+//<editor-fold desc="Block related types">
+
+// Block contains the header information of a block on a shard and the hashes of its transactions
+type Block struct {
+	Number          uint64
+	Hash            string
+	ShardID         uint
+	Timestamp       uint64
+	Leader          Address
+	GasUsed         uint64
+	TxHashes        []string
+	StakingTxHashes []string
+}
+
+//</editor-fold>
+
 //<editor-fold desc="Transaction related types">
 const (
 	TxSuccessful = 1
@@ -46,6 +88,7 @@ const (
 type TransactionLog struct {
 	TxHash   string
 	LogIndex int
+	BlockNum uint64
 	Address  Address
 	Topics   []string
 	Data     string
@@ -70,6 +113,50 @@ type Transaction struct {
 	ToShardID uint
 }
 
+// CXReceipt is the receipt of a cross shard transfer on its destination shard
+type CXReceipt struct {
+	TxHash    string
+	BlockHash string
+	BlockNum  uint64
+	Sender    Address
+	Receiver  Address
+	Amount    *big.Int
+	ShardID   uint
+	ToShardID uint
+}
+
+// CrossShardTransfer joins a transaction sending funds to another shard with the receipt on that shard.
+// Receipt is nil as long as the transfer did not arrive
+type CrossShardTransfer struct {
+	Tx      Transaction
+	Receipt *CXReceipt
+}
+
+const (
+	StakingCreateValidator = "CreateValidator"
+	StakingEditValidator   = "EditValidator"
+	StakingDelegate        = "Delegate"
+	StakingUndelegate      = "Undelegate"
+	StakingCollectRewards  = "CollectRewards"
+)
+
+// StakingTransaction contains all relevant information of a staking transaction.
+// Amount is the delegated, undelegated, self delegated or collected amount
+type StakingTransaction struct {
+	TxHash    string
+	Type      string
+	Sender    Address
+	Delegator Address
+	Validator Address
+	Amount    *big.Int
+	BlockNum  uint64
+	Timestamp uint64
+	Logs      []TransactionLog
+	Status    int
+	GasAmount uint32
+	GasPrice  *big.Int
+}
+
 // TokenTransaction contains a decoded transfer event, the hash of the transaction that caused the transfer
 type TokenTransaction struct {
 	TxHash   string
@@ -6,6 +6,8 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/hmybebop"
 	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -31,6 +33,49 @@ func TestTransactionBebop(t *testing.T) {
 	}
 }
 
+func TestStakingTransactionBebop(t *testing.T) {
+	t.Parallel()
+	in := &types.StakingTransaction{
+		TxHash:    "0x5555555555555555555555555555555555555555555555555555555555555555",
+		Type:      types.StakingDelegate,
+		Sender:    types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua"),
+		Delegator: types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua"),
+		Validator: types.NewAddress("one1t8auuy8kl30ujqt2u229273r2eshvhzpu59sz6"),
+		Amount:    new(big.Int).Exp(big.NewInt(10), big.NewInt(21), nil),
+		BlockNum:  25000000,
+		Logs:      []types.TransactionLog{{LogIndex: 3, Topics: []string{"0x" + strings.Repeat("ab", 32)}, Data: "0x01"}},
+		Status:    types.TxSuccessful,
+		GasPrice:  big.NewInt(30000000000),
+	}
+	data, err := hmybebop.EncodeStakingTransaction(in)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	out, err := hmybebop.DecodeStakingTransaction(data)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if in.TxHash != out.TxHash || in.Type != out.Type {
+		t.Errorf("Processed staking transaction does not have same hash or type: %s|%s", in.TxHash+in.Type, out.TxHash+out.Type)
+	}
+	if in.Validator.HexAddress != out.Validator.HexAddress {
+		t.Errorf("Processed staking transaction does not have same validator: %s|%s", in.Validator.HexAddress, out.Validator.HexAddress)
+	}
+	if in.Amount.String() != out.Amount.String() {
+		t.Errorf("Processed staking transaction does not have same amount: %s|%s", in.Amount.String(), out.Amount.String())
+	}
+	if len(out.Logs) != 1 || out.Logs[0].Topics[0] != in.Logs[0].Topics[0] || out.Logs[0].TxHash != in.TxHash {
+		t.Errorf("Processed staking transaction does not have same logs: %v", out.Logs)
+	}
+
+	// Amount and gas price are optional
+	_, err = hmybebop.EncodeStakingTransaction(&types.StakingTransaction{TxHash: in.TxHash, Type: types.StakingCollectRewards})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+}
+
 func BenchmarkBebopEncode(b *testing.B) {
 	var data []byte
 	for i := 0; i < b.N; i++ {
@@ -66,3 +111,25 @@ func BenchmarkGobDecode(b *testing.B) {
 	}
 	dump = tmp
 }
+
+func TestTokenBebop(t *testing.T) {
+	t.Parallel()
+	in := &types.Token{
+		Address:  types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua"),
+		Name:     "Wrapped ONE",
+		Symbol:   "WONE",
+		Decimals: 18,
+	}
+	data, err := hmybebop.EncodeToken(in)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	out, err := hmybebop.DecodeToken(data)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if *in != *out {
+		t.Errorf("Processed token is not the same: %v|%v", *in, *out)
+	}
+}
@@ -1,9 +1,17 @@
 package test
 
 import (
+	"bytes"
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/hmydecode"
-	"github.com/mjmar01/harmolytics/pkg/hmyload"
+	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
+	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -13,11 +21,8 @@ const (
 
 func TestDecodeSwap(t *testing.T) {
 	t.Parallel()
-	ldr, err := hmyload.NewLoader(url, &hmyload.Opts{ExistingCache: centralCache})
+	ldr := fixtureLoader(t)
 	defer ldr.Close()
-	if err != nil {
-		t.Fatal(err.(*errors.Error).ErrorStack())
-	}
 	txs, err := ldr.GetFullTransactions(swapTx)
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -44,11 +49,8 @@ func TestDecodeSwap(t *testing.T) {
 
 func TestDecodeTokenTransfers(t *testing.T) {
 	t.Parallel()
-	ldr, err := hmyload.NewLoader(url, &hmyload.Opts{ExistingCache: centralCache})
+	ldr := fixtureLoader(t)
 	defer ldr.Close()
-	if err != nil {
-		t.Error(err.(*errors.Error).ErrorStack())
-	}
 	txs, err := ldr.GetFullTransactions(swapTx)
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -69,3 +71,143 @@ func TestDecodeTokenTransfers(t *testing.T) {
 		t.Errorf("DecodeTokenTransaction returned incorrect Token amount: %s", tkTxs[0].Amount.String())
 	}
 }
+
+const orderAbi = `[{"type":"function","name":"fill","stateMutability":"nonpayable","outputs":[],"inputs":[
+	{"name":"order","type":"tuple","components":[{"name":"maker","type":"address"},{"name":"amounts","type":"uint256[2]"}]},
+	{"name":"data","type":"bytes"},
+	{"name":"id","type":"bytes32"},
+	{"name":"delta","type":"int8"},
+	{"name":"path","type":"address[]"},
+	{"name":"flag","type":"bool"}
+]}]`
+
+func TestDecodeCall(t *testing.T) {
+	t.Parallel()
+	contract := types.NewAddress("0x1234567890123456789012345678901234567890")
+	maker := types.NewAddress("0xcf664087a5bb0237a0bad6742852ec6c8d69a27a")
+	registry := hmydecode.NewAbiRegistry()
+	err := registry.Register(contract, strings.NewReader(orderAbi))
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	parsed, _ := abi.JSON(strings.NewReader(orderAbi))
+	order := struct {
+		Maker   common.Address
+		Amounts [2]*big.Int
+	}{common.HexToAddress(maker.HexAddress), [2]*big.Int{big.NewInt(7), big.NewInt(8)}}
+	input, err := parsed.Pack("fill", order, []byte{1, 2, 3}, [32]byte{9}, int8(-5), []common.Address{common.HexToAddress(maker.HexAddress)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.Transaction{TxHash: swapTx, Receiver: contract, Input: "0x" + hex.EncodeToString(input)}
+	c, ok, err := registry.DecodeCall(tx)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if !ok || c.Method.Name != "fill" || len(c.Arguments) != 6 {
+		t.Fatalf("DecodeCall returned incorrect call: %v", c)
+	}
+	components := c.Arguments[0].Value.([]types.Argument)
+	if c.Arguments[0].Type != "(address,uint256[2])" || components[0].Name != "maker" || components[0].Value != maker {
+		t.Errorf("DecodeCall returned incorrect tuple: %v", c.Arguments[0])
+	}
+	if amounts := components[1].Value.([]interface{}); amounts[1].(*big.Int).Int64() != 8 {
+		t.Errorf("DecodeCall returned incorrect fixed array: %v", amounts)
+	}
+	if !bytes.Equal(c.Arguments[1].Value.([]byte), []byte{1, 2, 3}) || c.Arguments[2].Value.([]byte)[0] != 9 || len(c.Arguments[2].Value.([]byte)) != 32 {
+		t.Errorf("DecodeCall returned incorrect bytes: %v %v", c.Arguments[1], c.Arguments[2])
+	}
+	if c.Arguments[3].Value.(*big.Int).Int64() != -5 || c.Arguments[4].Value.([]interface{})[0] != maker || c.Arguments[5].Value != true {
+		t.Errorf("DecodeCall returned incorrect values: %v", c.Arguments[3:])
+	}
+	// Other contracts are unknown
+	tx.Receiver = maker
+	if _, ok, _ = registry.DecodeCall(tx); ok {
+		t.Errorf("DecodeCall decoded a call to a contract without ABI")
+	}
+}
+
+func TestDecodeSwapAmounts(t *testing.T) {
+	t.Parallel()
+	pair := types.NewAddress("0xcf664087a5bb0237a0bad6742852ec6c8d69a27a")
+	swapData, _ := hmysolidityio.EncodeAll(big.NewInt(0), big.NewInt(42), big.NewInt(7), big.NewInt(0))
+	swp, ok, err := hmydecode.DecodeSwap(types.Transaction{
+		TxHash: swapTx,
+		Method: types.Method{Signature: "8803dbee"},
+		Input:  testSwapInput,
+		Logs: []types.TransactionLog{{
+			TxHash:  swapTx,
+			Address: pair,
+			Topics:  []string{"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822"},
+			Data:    "0x" + swapData,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if !ok || swp.InAmount.Int64() != 42 || swp.OutAmount.Int64() != 7 {
+		t.Errorf("DecodeSwap returned incorrect amounts: %v %v", swp.InAmount, swp.OutAmount)
+	}
+	if swp.OutToken.Address.HexAddress != "0x58f1b044d8308812881a1433d9bbeff99975e70c" {
+		t.Errorf("DecodeSwap returned incorrect OutToken: %s", swp.OutToken.Address.HexAddress)
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	t.Parallel()
+	pair := types.NewAddress("0xcf664087a5bb0237a0bad6742852ec6c8d69a27a")
+	wallet := "0x000000000000000000000000cf664087a5bb0237a0bad6742852ec6c8d69a27a"
+	swapData, _ := hmysolidityio.EncodeAll(big.NewInt(1), big.NewInt(0), big.NewInt(0), big.NewInt(4))
+	registry := hmydecode.NewAbiRegistry()
+	e, ok, err := registry.DecodeEvent(types.TransactionLog{
+		TxHash:  swapTx,
+		Address: pair,
+		Topics:  []string{"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822", wallet, wallet},
+		Data:    "0x" + swapData,
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if !ok || e.Name != "Swap" || len(e.Arguments) != 6 || e.Address != pair {
+		t.Fatalf("DecodeEvent returned incorrect event: %v", e)
+	}
+	if e.Arguments[0].Name != "sender" || e.Arguments[0].Value != pair || e.Arguments[4].Name != "amount1Out" || e.Arguments[4].Value.(*big.Int).Int64() != 4 {
+		t.Errorf("DecodeEvent returned incorrect arguments: %v", e.Arguments)
+	}
+
+	// HRC-721 transfers index the token ID
+	e, ok, err = registry.DecodeEvent(types.TransactionLog{
+		Topics: []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", wallet, wallet, "0x" + strings.Repeat("0", 62) + "2a"},
+		Data:   "0x",
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if !ok || e.Name != "Transfer" || e.Arguments[2].Name != "tokenId" || e.Arguments[2].Value.(*big.Int).Int64() != 42 {
+		t.Errorf("DecodeEvent returned incorrect HRC-721 transfer: %v", e)
+	}
+
+	// Custom events with indexed dynamic values
+	err = registry.Register(pair, strings.NewReader(`[{"type":"event","name":"Named","inputs":[{"name":"name","type":"string","indexed":true},{"name":"flag","type":"bool","indexed":false}]}]`))
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	nameHash := "0x" + strings.Repeat("ab", 32)
+	flag := "0x" + strings.Repeat("0", 63) + "1"
+	e, ok, err = registry.DecodeEvent(types.TransactionLog{
+		Topics: []string{"0x" + hex.EncodeToString(crypto.Keccak256([]byte("Named(string,bool)"))), nameHash},
+		Data:   flag,
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if !ok || "0x"+hex.EncodeToString(e.Arguments[0].Value.([]byte)) != nameHash || e.Arguments[1].Value != true {
+		t.Errorf("DecodeEvent returned incorrect custom event: %v", e)
+	}
+	if _, ok, _ = registry.DecodeEvent(types.TransactionLog{Topics: []string{nameHash}}); ok {
+		t.Errorf("DecodeEvent decoded an unknown event")
+	}
+}
@@ -1,23 +1,27 @@
 package test
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/hmyload"
+	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
 	"github.com/mjmar01/harmolytics/pkg/rpc"
 	"github.com/mjmar01/harmolytics/pkg/types"
+	"math/big"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func TestGetHistory(t *testing.T) {
 	t.Parallel()
-	l, err := hmyload.NewLoader(url, &hmyload.Opts{AdditionalConnections: 10, ExistingCache: centralCache})
+	l := fixtureLoader(t)
 	defer l.Close()
-	if err != nil {
-		t.Fatal(err.(*errors.Error).ErrorStack())
-	}
 	txs, err := l.GetTransactionsByWallet(types.NewAddress("0x42813a05ec9c7e17af2d1499f9b0a591b7619abf"))
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -33,11 +37,8 @@ func TestGetHistory(t *testing.T) {
 
 func TestGetFullTransaction(t *testing.T) {
 	t.Parallel()
-	l, err := hmyload.NewLoader(url, &hmyload.Opts{ExistingCache: centralCache})
+	l := fixtureLoader(t)
 	defer l.Close()
-	if err != nil {
-		t.Fatal(err.(*errors.Error).ErrorStack())
-	}
 	txs, err := l.GetFullTransactions("0xf916accb28b218085da083f2df398d66f65ce175e32a38ea232debf708b2cc84", "0xf916accb28b218085da083f2df398d66f65ce175e32a38ea232debf708b2cc84")
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -59,11 +60,8 @@ func TestGetFullTransaction(t *testing.T) {
 
 func TestGetTokens(t *testing.T) {
 	t.Parallel()
-	l, err := hmyload.NewLoader(url, &hmyload.Opts{ExistingCache: centralCache})
+	l := fixtureLoader(t)
 	defer l.Close()
-	if err != nil {
-		t.Fatal(err.(*errors.Error).ErrorStack())
-	}
 	tks, err := l.GetTokens(
 		types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua"),
 		types.NewAddress("one1t8auuy8kl30ujqt2u229273r2eshvhzpu59sz6"),
@@ -85,10 +83,16 @@ func TestGetTokens(t *testing.T) {
 }
 
 func TestAltHistory(t *testing.T) {
+	nodeUrl := mainnetUrl(t)
+	r, err := rpc.NewRPC(nodeUrl, nil)
+	defer r.Close()
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
 	t1 := time.Now()
 	var testBodies []rpc.Body
 	for i := 0; i < 80; i++ {
-		testBodies = append(testBodies, defaultRPC.NewBody(
+		testBodies = append(testBodies, r.NewBody(
 			"hmyv2_getTransactionsHistory",
 			map[string]interface{}{
 				"address":   "0x42813a05ec9c7e17af2d1499f9b0a591b7619abf",
@@ -101,7 +105,7 @@ func TestAltHistory(t *testing.T) {
 	}
 	fmt.Printf("Allocate bodies: %s\n", time.Since(t1))
 	t1 = time.Now()
-	ress, err := defaultRPC.BatchCall(testBodies)
+	ress, err := r.RawBatchCall(testBodies)
 	fmt.Println(err)
 	sum := 0
 	for _, res := range ress {
@@ -111,16 +115,912 @@ func TestAltHistory(t *testing.T) {
 	testBodies = []rpc.Body{}
 	t1 = time.Now()
 	for i := 0; i < sum; i++ {
-		testBodies = append(testBodies, defaultRPC.NewBody("hmyv2_getTransactionReceipt", "0x771d2da16e07d81c63f2e7cf22418e5e98b5b57438de8005f5b144cfbe6867ba"))
+		testBodies = append(testBodies, r.NewBody("hmyv2_getTransactionReceipt", "0x771d2da16e07d81c63f2e7cf22418e5e98b5b57438de8005f5b144cfbe6867ba"))
 	}
 	fmt.Printf("Allocate bodies: %s\n", time.Since(t1))
 
 	t1 = time.Now()
 	for i := 0; i < 20000; i += 5000 {
-		ress, err = defaultRPC.RawBatchCall(testBodies[i : i+5000])
+		ress, err = r.RawBatchCall(testBodies[i : i+5000])
 		if err != nil {
 			panic(err)
 		}
 	}
 	fmt.Printf("Batch Call: %s\n", time.Since(t1))
 }
+
+func TestLoaderHttp(t *testing.T) {
+	t.Parallel()
+	n := newLocalHttpNode(tokenHandler)
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	tks, err := l.GetTokens(types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua"))
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if tks[0].Symbol != "WONE" || tks[0].Decimals != 18 {
+		t.Errorf("Result did contain incorrect token: %v", tks[0])
+	}
+}
+
+func TestLoaderMemoryClient(t *testing.T) {
+	t.Parallel()
+	hash := "0x1111111111111111111111111111111111111111111111111111111111111111"
+	c := rpc.NewMemoryClient("memory")
+	c.Set(map[string]interface{}{
+		"hash":        hash,
+		"ethHash":     hash,
+		"from":        "one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua",
+		"to":          "one1t8auuy8kl30ujqt2u229273r2eshvhzpu59sz6",
+		"timestamp":   1650000000,
+		"gas":         21000,
+		"gasPrice":    30000000000,
+		"input":       "0x",
+		"value":       1000,
+		"shardID":     0,
+		"toShardID":   0,
+		"blockNumber": 25000000,
+	}, "hmyv2_getTransactionByHash", hash)
+	c.Set(map[string]interface{}{
+		"transactionHash": hash,
+		"status":          1,
+		"logs":            []interface{}{},
+	}, "hmyv2_getTransactionReceipt", hash)
+	l, err := hmyload.NewLoader("", &hmyload.Opts{ExistingCache: centralCache, ExistingClients: []rpc.Client{c}})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	txs, err := l.GetFullTransactions(hash)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if txs[0].BlockNum != 25000000 || txs[0].Status != types.TxSuccessful {
+		t.Errorf("Result did not contain correct transaction: %v", txs[0])
+	}
+	if txs[0].Value.Int64() != 1000 {
+		t.Errorf("Result did not contain correct Value: %s", txs[0].Value)
+	}
+}
+
+func TestLoaderContext(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		<-release
+		return nil
+	})
+	defer n.Close()
+	defer close(release)
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache, AdditionalConnections: 1})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = l.GetFullTransactionsContext(ctx, "0x2222222222222222222222222222222222222222222222222222222222222222")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetFullTransactionsContext did not stop at the deadline: %v", err)
+	}
+	// Cached tokens would be returned without waiting for the node
+	_, err = l.GetTokensContext(ctx, types.NewAddress("0x5555555555555555555555555555555555555555"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetTokensContext did not stop at the deadline: %v", err)
+	}
+}
+
+func TestLoaderRateLimit(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight int64
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		cur := atomic.AddInt64(&inFlight, 1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if cur <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, cur) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+		return tokenHandler(method, params)
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{
+		ExistingCache:         centralCache,
+		AdditionalConnections: 2,
+		RequestsPerSecond:     20,
+		MaxInFlight:           4,
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	addrs := make([]types.Address, 4)
+	for i := range addrs {
+		addrs[i] = types.NewAddress("0x4444444444444444444444444444444444444444")
+	}
+	t1 := time.Now()
+	tks, err := l.GetTokens(addrs...)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	// 12 bodies in chunks of 4 at 20 requests per second
+	if time.Since(t1) < 100*time.Millisecond {
+		t.Errorf("Requests were not rate limited: %s", time.Since(t1))
+	}
+	if max := atomic.LoadInt64(&maxInFlight); max > 4 {
+		t.Errorf("Node received %d bodies at once", max)
+	}
+	if tks[3].Symbol != "WONE" {
+		t.Errorf("Result did contain incorrect token: %v", tks[3])
+	}
+}
+
+func TestStreamTransactionsByWallet(t *testing.T) {
+	t.Parallel()
+	wallet := types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua")
+	hashes := []string{
+		"0x3333333333333333333333333333333333333333333333333333333333333331",
+		"0x3333333333333333333333333333333333333333333333333333333333333332",
+		"0x3333333333333333333333333333333333333333333333333333333333333333",
+	}
+	c := rpc.NewMemoryClient("memory")
+	// Pages overlap, hashes have to be deduplicated
+	memoryHistory(c, wallet, 2, 50, hashes...)
+	for i, hash := range hashes {
+		memoryTx(c, wallet, hash, map[string]interface{}{"blockNumber": 25000000 + i})
+	}
+	l := memoryLoader(t, c, hmyload.Opts{HistoryPageSize: 2, TransactionChunkSize: 2})
+
+	for run := 0; run < 2; run++ {
+		var reports []hmyload.WalletProgress
+		txCh, errCh := l.StreamTransactionsByWallet(context.Background(), wallet, func(p hmyload.WalletProgress) {
+			reports = append(reports, p)
+		})
+		i := 0
+		for tx := range txCh {
+			if tx.TxHash != hashes[i] {
+				t.Errorf("Stream returned transaction out of order at position %d: %s", i, tx.TxHash)
+			}
+			i++
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+
+		// Two history pages and two chunks
+		if len(reports) != 4 || i != 3 {
+			t.Fatalf("Stream reported incorrect progress: %v", reports)
+		}
+		last := reports[3]
+		if last.PagesFetched != 2 || last.Txs != 3 || last.TxsLoaded != 3 || last.CacheHits != run*3 {
+			t.Errorf("Stream reported incorrect progress in run %d: %v", run, last)
+		}
+	}
+}
+
+func TestSyncWallet(t *testing.T) {
+	t.Parallel()
+	wallet := types.NewAddress("one1t8auuy8kl30ujqt2u229273r2eshvhzpu59sz6")
+	hashes := []string{
+		"0x4444444444444444444444444444444444444444444444444444444444444441",
+		"0x4444444444444444444444444444444444444444444444444444444444444442",
+		"0x4444444444444444444444444444444444444444444444444444444444444443",
+	}
+	c := rpc.NewMemoryClient("memory")
+	for i, hash := range hashes {
+		memoryTx(c, wallet, hash, map[string]interface{}{"blockNumber": 26000000 + i})
+	}
+	l := memoryLoader(t, c, hmyload.Opts{HistoryPageSize: 2})
+
+	// First sync loads the whole history, later syncs only what was added
+	for _, step := range []struct {
+		count int
+		want  []string
+	}{{2, hashes[:2]}, {3, hashes[2:]}, {3, nil}} {
+		memoryHistory(c, wallet, 2, 0, hashes[:step.count]...)
+		txs, err := l.SyncWallet(wallet)
+		if err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+		if len(txs) != len(step.want) {
+			t.Fatalf("Sync returned %d instead of %d transactions", len(txs), len(step.want))
+		}
+		for i, tx := range txs {
+			if tx.TxHash != step.want[i] {
+				t.Errorf("Sync returned incorrect transaction at position %d: %s", i, tx.TxHash)
+			}
+		}
+	}
+	cursor, ok := centralCache.GetWalletCursor(0, wallet)
+	if !ok || cursor.TxCount != 3 || cursor.LastBlock != 26000002 {
+		t.Errorf("Cache contains incorrect cursor: %v", cursor)
+	}
+
+	// Malformed results fail the sync
+	c.Set("0x4", "hmyv2_getTransactionsCount", wallet.OneAddress, rpc.AllTx)
+	if _, err := l.SyncWallet(wallet); err == nil {
+		t.Errorf("Sync accepted a malformed transaction count")
+	}
+	c.Set(4, "hmyv2_getTransactionsCount", wallet.OneAddress, rpc.AllTx)
+	c.SetRaw([]byte("0"), "hmyv2_getTransactionsHistory", map[string]interface{}{
+		"address":   wallet.OneAddress,
+		"pageIndex": 1,
+		"pageSize":  2,
+		"fullTx":    false,
+		"txType":    rpc.AllTx,
+		"order":     "ASC",
+	})
+	if _, err := l.SyncWallet(wallet); err == nil {
+		t.Errorf("Sync accepted a malformed history")
+	}
+}
+
+func TestGetStakingTransactionsByWallet(t *testing.T) {
+	t.Parallel()
+	delegator := types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua")
+	validator := types.NewAddress("one1t8auuy8kl30ujqt2u229273r2eshvhzpu59sz6")
+	hashes := []string{
+		"0x6666666666666666666666666666666666666666666666666666666666666661",
+		"0x6666666666666666666666666666666666666666666666666666666666666662",
+	}
+	c := rpc.NewMemoryClient("memory")
+	c.Set(2, "hmyv2_getStakingTransactionsCount", delegator.OneAddress, rpc.AllTx)
+	c.Set(map[string]interface{}{"staking_transactions": hashes}, "hmyv2_getStakingTransactionsHistory", map[string]interface{}{
+		"address":   delegator.OneAddress,
+		"pageIndex": 0,
+		"pageSize":  50000,
+		"fullTx":    false,
+		"txType":    rpc.AllTx,
+		"order":     "ASC",
+	})
+	c.SetRaw([]byte(`{"hash":"`+hashes[0]+`","type":"Delegate","from":"`+delegator.OneAddress+`","blockNumber":25000000,"gas":25000,"gasPrice":30000000000,
+		"msg":{"amount":1000000000000000000000,"delegatorAddress":"`+delegator.OneAddress+`","validatorAddress":"`+validator.OneAddress+`"}}`),
+		"hmyv2_getStakingTransactionByHash", hashes[0])
+	c.SetRaw([]byte(`{"hash":"`+hashes[1]+`","type":"CollectRewards","from":"`+delegator.OneAddress+`","blockNumber":25000001,"gas":25000,"gasPrice":30000000000,
+		"msg":{"delegatorAddress":"`+delegator.OneAddress+`"}}`),
+		"hmyv2_getStakingTransactionByHash", hashes[1])
+	memoryReceipt(c, hashes[0])
+	// Collected rewards are logged as minimal big endian bytes
+	c.Set(map[string]interface{}{"transactionHash": hashes[1], "status": 1, "logs": []interface{}{map[string]interface{}{
+		"address":         delegator.HexAddress,
+		"topics":          []string{"0x0a966e2194340fb41dcff09c36e1033736587e629b3b4da18a7d5ac24e924ba6"},
+		"data":            "0x0de0b6b3a7640000",
+		"logIndex":        "0x0",
+		"transactionHash": hashes[1],
+	}}}, "hmyv2_getTransactionReceipt", hashes[1])
+	l := memoryLoader(t, c, hmyload.Opts{})
+	txs, err := l.GetStakingTransactionsByWallet(delegator)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if len(txs) != 2 || txs[0].Type != types.StakingDelegate || txs[1].Type != types.StakingCollectRewards {
+		t.Fatalf("Result did not contain correct staking transactions: %v", txs)
+	}
+	if txs[0].Validator != validator || txs[0].Amount.String() != "1000000000000000000000" {
+		t.Errorf("Result did not contain correct delegation: %v", txs[0])
+	}
+	if txs[1].Delegator != delegator || txs[1].Amount.String() != "1000000000000000000" || txs[1].Status != types.TxSuccessful {
+		t.Errorf("Result did not contain correct reward collection: %v", txs[1])
+	}
+}
+
+// memoryLoader returns a Loader on the central cache using c for its default shard. It is closed with the test
+func memoryLoader(t *testing.T, c rpc.Client, opts hmyload.Opts) *hmyload.Loader {
+	opts.ExistingCache = centralCache
+	opts.ExistingClients = []rpc.Client{c}
+	l, err := hmyload.NewLoader("", &opts)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	t.Cleanup(l.Close)
+	return l
+}
+
+// memoryHistory stores the transaction count and history of a wallet in c. Pages are requested with pageSize+overlap
+// entries, so each page also holds the first overlap entries of the next one
+func memoryHistory(c *rpc.MemoryClient, wallet types.Address, pageSize, overlap int, hashes ...string) {
+	c.Set(len(hashes), "hmyv2_getTransactionsCount", wallet.OneAddress, rpc.AllTx)
+	for page := 0; page == 0 || page*pageSize < len(hashes); page++ {
+		end := (page+1)*pageSize + overlap
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		c.Set(map[string]interface{}{"transactions": hashes[page*pageSize : end]}, "hmyv2_getTransactionsHistory", map[string]interface{}{
+			"address":   wallet.OneAddress,
+			"pageIndex": page,
+			"pageSize":  pageSize + overlap,
+			"fullTx":    false,
+			"txType":    rpc.AllTx,
+			"order":     "ASC",
+		})
+	}
+}
+
+// memoryTx stores a transfer of wallet to itself and its receipt in c. fields replace the defaults
+func memoryTx(c *rpc.MemoryClient, wallet types.Address, hash string, fields map[string]interface{}) {
+	tx := map[string]interface{}{
+		"hash":     hash,
+		"ethHash":  hash,
+		"from":     wallet.OneAddress,
+		"to":       wallet.OneAddress,
+		"input":    "0x",
+		"value":    1000,
+		"gasPrice": 30000000000,
+	}
+	for k, v := range fields {
+		tx[k] = v
+	}
+	c.Set(tx, "hmyv2_getTransactionByHash", hash)
+	memoryReceipt(c, hash)
+}
+
+// memoryReceipt stores a successful receipt without logs in c
+func memoryReceipt(c *rpc.MemoryClient, hash string) {
+	c.Set(map[string]interface{}{"transactionHash": hash, "status": 1, "logs": []interface{}{}}, "hmyv2_getTransactionReceipt", hash)
+}
+
+func TestMultiShard(t *testing.T) {
+	t.Parallel()
+	wallet := types.NewAddress("0x7777777777777777777777777777777777777777")
+	crossHash := "0x7777777777777777777777777777777777777777777777777777777777777770"
+	shard1Hash := "0x7777777777777777777777777777777777777777777777777777777777777771"
+	shard0, shard1 := rpc.NewMemoryClient("shard0"), rpc.NewMemoryClient("shard1")
+	memoryHistory(shard0, wallet, 50000, 50, crossHash)
+	memoryTx(shard0, wallet, crossHash, map[string]interface{}{"toShardID": 1})
+	memoryHistory(shard1, wallet, 50000, 50, shard1Hash)
+	memoryTx(shard1, wallet, shard1Hash, nil)
+	shard1.Set(map[string]interface{}{
+		"hash":        crossHash,
+		"blockHash":   "0x8888888888888888888888888888888888888888888888888888888888888888",
+		"blockNumber": 22000000,
+		"from":        wallet.OneAddress,
+		"to":          wallet.OneAddress,
+		"value":       1000,
+		"shardID":     0,
+		"toShardID":   1,
+	}, "hmyv2_getCXReceiptByHash", crossHash)
+	l := memoryLoader(t, shard0, hmyload.Opts{ShardClients: map[uint][]rpc.Client{1: {shard1}}})
+	txs, err := l.GetTransactionsByWallet(wallet)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(txs) != 2 || txs[0].TxHash != crossHash || txs[1].TxHash != shard1Hash {
+		t.Fatalf("Result did not contain transactions of both shards: %v", txs)
+	}
+
+	// Shard 1 is a valid destination, shard 2 is not
+	transfers, err := l.GetCrossShardTransfers(txs...)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(transfers) != 1 || transfers[0].Receipt == nil {
+		t.Fatalf("Result did not contain the cross shard transfer: %v", transfers)
+	}
+	sent, received := transfers[0].Tx, transfers[0].Receipt
+	if sent.TxHash != received.TxHash || sent.ShardID != received.ShardID || sent.ToShardID != received.ToShardID ||
+		received.Receiver != wallet || received.BlockNum != 22000000 || received.Amount.Int64() != 1000 {
+		t.Errorf("Transfer was not joined with its receipt: %v %v", sent, received)
+	}
+	txs[0].ToShardID = 2
+	_, err = l.GetCrossShardTransfers(txs[0])
+	if err == nil {
+		t.Errorf("Transfer to unknown shard did not fail")
+	}
+
+	// A shard failing mid-sync leaves the cursors of all shards untouched
+	syncHash := "0x7777777777777777777777777777777777777777777777777777777777777772"
+	memoryHistory(shard0, wallet, 50000, 0, crossHash)
+	memoryHistory(shard1, wallet, 50000, 0, shard1Hash, syncHash)
+	memoryTx(shard1, wallet, syncHash, nil)
+	shard1.SetError(&rpc.Error{Code: rpc.ServerErrorCode, Message: "busy"}, "hmyv2_getTransactionReceipt", syncHash)
+	if _, err = l.SyncWallet(wallet); err == nil {
+		t.Fatalf("Sync did not fail with a failing shard")
+	}
+	if cursor, ok := centralCache.GetWalletCursor(0, wallet); ok {
+		t.Errorf("Sync moved the cursor of shard 0 although shard 1 failed: %v", cursor)
+	}
+	memoryReceipt(shard1, syncHash)
+	txs, err = l.SyncWallet(wallet)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(txs) != 3 || txs[0].TxHash != crossHash || txs[2].TxHash != syncHash {
+		t.Errorf("Repeated sync did not return transactions of both shards: %v", txs)
+	}
+}
+
+func TestGetBlocks(t *testing.T) {
+	t.Parallel()
+	var calls int64
+	leader := types.NewAddress("one1eanyppa9hvpr0g966e6zs5hvdjxkngn6jtulua")
+	block := func(num float64) map[string]interface{} {
+		return map[string]interface{}{
+			"number":              num,
+			"hash":                fmt.Sprintf("0x%064x", int64(num)),
+			"timestamp":           1650000000 + num - 900000000,
+			"miner":               leader.OneAddress,
+			"gasUsed":             21000,
+			"transactions":        []string{fmt.Sprintf("0x%064x", int64(num)+1)},
+			"stakingTransactions": []string{},
+		}
+	}
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		atomic.AddInt64(&calls, 1)
+		num := params[0].(float64)
+		if method != "hmyv2_getBlockByNumber" || num > 900000104 {
+			return nil
+		}
+		return block(num)
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache, AdditionalConnections: 2})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	blocks, err := l.GetBlocks(900000100, 900000104)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("Result did not contain 5 blocks: %v", blocks)
+	}
+	for i, b := range blocks {
+		if b.Number != uint64(900000100+i) || b.Leader != leader || len(b.TxHashes) != 1 {
+			t.Errorf("Result did not contain correct block at position %d: %v", i, b)
+		}
+	}
+
+	// Blocks are served from the cache now
+	before := atomic.LoadInt64(&calls)
+	blocks, err = l.GetBlocks(900000102, 900000104)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if atomic.LoadInt64(&calls) != before || blocks[0].Hash != fmt.Sprintf("0x%064x", 900000102) {
+		t.Errorf("Cached blocks were not used: %v", blocks[0])
+	}
+	if blocks[2].TxHashes[0] != fmt.Sprintf("0x%064x", 900000105) {
+		t.Errorf("Cached block does not have same transactions: %v", blocks[2].TxHashes)
+	}
+	_, err = l.GetBlocks(900000104, 900000105)
+	if err == nil {
+		t.Errorf("Loading a block that does not exist did not fail")
+	}
+
+	// Large ranges are requested in chunks
+	c := &batchClient{MemoryClient: rpc.NewMemoryClient("memory")}
+	for num := 900000200; num < 900000205; num++ {
+		c.Set(block(float64(num)), "hmyv2_getBlockByNumber", num, map[string]bool{"fullTx": false, "inclTx": true, "inclStaking": true})
+	}
+	blocks, err = memoryLoader(t, c, hmyload.Opts{TransactionChunkSize: 2}).GetBlocks(900000200, 900000204)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(blocks) != 5 || fmt.Sprint(c.sizes) != "[2 2 1]" {
+		t.Errorf("Blocks were not requested in chunks of 2: %v", c.sizes)
+	}
+}
+
+// batchClient records the size of every batch sent through it
+type batchClient struct {
+	*rpc.MemoryClient
+	sizes []int
+	mutex sync.Mutex
+}
+
+func (c *batchClient) RawBatchCallContext(ctx context.Context, bodies []rpc.Body) ([][]byte, error) {
+	c.mutex.Lock()
+	c.sizes = append(c.sizes, len(bodies))
+	c.mutex.Unlock()
+	return c.MemoryClient.RawBatchCallContext(ctx, bodies)
+}
+
+func TestGetLogs(t *testing.T) {
+	t.Parallel()
+	pair := types.NewAddress("0xcf664087a5bb0237a0bad6742852ec6c8d69a27a")
+	swapTopic := "0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822"
+	var windows []string
+	var mutex sync.Mutex
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		filter := params[0].(map[string]interface{})
+		from, _ := hexutil.DecodeUint64(filter["fromBlock"].(string))
+		to, _ := hexutil.DecodeUint64(filter["toBlock"].(string))
+		mutex.Lock()
+		windows = append(windows, fmt.Sprintf("%d-%d", from, to))
+		mutex.Unlock()
+		// Like public nodes, refuse large windows
+		if to-from >= 4 {
+			return &rpc.Error{Code: rpc.ServerErrorCode, Message: "query must be smaller than size 4"}
+		}
+		if filter["address"].([]interface{})[0] != pair.HexAddress || filter["topics"].([]interface{})[0].([]interface{})[0] != swapTopic {
+			return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "unexpected filter"}
+		}
+		// One log every 3 blocks
+		var logs []interface{}
+		for num := from; num <= to; num++ {
+			if num%3 == 0 {
+				logs = append(logs, map[string]interface{}{
+					"address":         pair.HexAddress,
+					"topics":          []string{swapTopic},
+					"data":            "0x",
+					"blockNumber":     hexutil.EncodeUint64(num),
+					"transactionHash": fmt.Sprintf("0x%064x", num),
+					"logIndex":        "0x1",
+				})
+			}
+		}
+		return logs
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache, LogBlockRange: 16})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	logs, err := l.GetLogs(hmyload.LogFilter{
+		FromBlock: 100,
+		ToBlock:   120,
+		Addresses: []types.Address{pair},
+		Topics:    [][]string{{swapTopic}},
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	// Blocks 102 to 120 contain 7 logs
+	if len(logs) != 7 {
+		t.Fatalf("Result contained %d instead of 7 logs. Windows: %v", len(logs), windows)
+	}
+	for i, log := range logs {
+		if log.BlockNum != uint64(102+3*i) || log.Address != pair {
+			t.Errorf("Result did not contain correct log at position %d: %v", i, log)
+		}
+	}
+	if windows[0] != "100-115" || windows[len(windows)-1] != "120-120" {
+		t.Errorf("Block range was not split correctly: %v", windows)
+	}
+	// The window grows again after a success
+	if windows[3] != "104-111" {
+		t.Errorf("Window did not grow after a success: %v", windows)
+	}
+
+	// Other errors are returned without splitting
+	calls := len(windows)
+	_, err = l.GetLogs(hmyload.LogFilter{FromBlock: 100, ToBlock: 101, Addresses: []types.Address{pair}, Topics: [][]string{{pair.HexAddress}}})
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpc.InvalidParamsCode {
+		t.Errorf("GetLogs did not return the node error: %v", err)
+	}
+	if len(windows) != calls+1 {
+		t.Errorf("GetLogs split the range on an unrelated error: %v", windows[calls:])
+	}
+}
+
+func TestSubscribeLogs(t *testing.T) {
+	t.Parallel()
+	pair := types.NewAddress("0xcf664087a5bb0237a0bad6742852ec6c8d69a27a")
+	swapTopic := "0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822"
+	subscribed := make(chan struct{}, 1)
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		if method != "eth_subscribe" {
+			return true
+		}
+		filter := params[1].(map[string]interface{})
+		if params[0] != "logs" || filter["address"].([]interface{})[0] != pair.HexAddress || filter["topics"].([]interface{})[0].([]interface{})[0] != swapTopic {
+			return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "unexpected filter"}
+		}
+		subscribed <- struct{}{}
+		return "0xabc"
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logCh, errCh, err := l.SubscribeLogs(ctx, hmyload.LogFilter{
+		Addresses: []types.Address{pair},
+		Topics:    [][]string{{swapTopic}},
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	<-subscribed
+	log := func(num uint64, removed bool) map[string]interface{} {
+		return map[string]interface{}{
+			"address":         pair.HexAddress,
+			"topics":          []string{swapTopic},
+			"data":            "0x",
+			"blockNumber":     hexutil.EncodeUint64(num),
+			"transactionHash": fmt.Sprintf("0x%064x", num),
+			"logIndex":        "0x0",
+			"removed":         removed,
+		}
+	}
+	// Removed logs are skipped
+	n.notify("0xabc", log(1, true))
+	n.notify("0xabc", log(2, false))
+	select {
+	case got := <-logCh:
+		if got.BlockNum != 2 || got.Address != pair || got.TxHash != fmt.Sprintf("0x%064x", 2) {
+			t.Errorf("Subscription delivered incorrect log: %v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscription did not deliver log")
+	}
+	cancel()
+	for range logCh {
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("Cancelled subscription returned error: %v", err)
+	}
+}
+
+func TestGetTokenBalances(t *testing.T) {
+	t.Parallel()
+	wallet := types.NewAddress("0x7777777777777777777777777777777777777777")
+	tokens := []types.Address{
+		types.NewAddress("0xcf664087a5bb0237a0bad6742852ec6c8d69a27a"),
+		types.NewAddress("0x985458e523db3d53125813ed68c274899e9dfab4"),
+	}
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		if params[1] != float64(1000) {
+			return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "unexpected block"}
+		}
+		switch method {
+		case "hmyv2_getBalanceByBlockNumber":
+			if params[0] != wallet.OneAddress {
+				return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "unexpected wallet"}
+			}
+			return 1000
+		case "hmyv2_call":
+			call := params[0].(map[string]interface{})
+			if !strings.HasSuffix(call["data"].(string), strings.ToLower(wallet.HexAddress[2:])) {
+				return &rpc.Error{Code: rpc.ServerErrorCode, Message: "execution reverted"}
+			}
+			out, _ := hmysolidityio.EncodeAll(big.NewInt(2000))
+			if call["to"] == tokens[1].HexAddress {
+				out, _ = hmysolidityio.EncodeAll(big.NewInt(3000))
+			}
+			return "0x" + out
+		}
+		return &rpc.Error{Code: rpc.MethodNotFoundCode, Message: "method not found"}
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache, AdditionalConnections: 1})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	balance, tokenBalances, err := l.GetTokenBalances(wallet, tokens, 1000)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if balance.Int64() != 1000 {
+		t.Errorf("Native balance was %s instead of 1000", balance)
+	}
+	if len(tokenBalances) != 2 || tokenBalances[0].Int64() != 2000 || tokenBalances[1].Int64() != 3000 {
+		t.Errorf("Token balances were %v instead of [2000 3000]", tokenBalances)
+	}
+}
+
+func TestGetReserves(t *testing.T) {
+	t.Parallel()
+	tokenX := types.Token{Address: types.NewAddress("0x1111111111111111111111111111111111111111")}
+	tokenY := types.Token{Address: types.NewAddress("0x2222222222222222222222222222222222222222")}
+	// TokenA is the second token of the pool contract
+	pool := types.LiquidityPool{TokenA: tokenY, TokenB: tokenX, LpToken: types.Token{Address: types.NewAddress("0x3333333333333333333333333333333333333333")}}
+	var calls int64
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		atomic.AddInt64(&calls, 1)
+		call := params[0].(map[string]interface{})
+		if method != "hmyv2_call" || call["to"] != pool.LpToken.Address.HexAddress || call["data"] != "0x0902f1ac" || params[1] != float64(999) {
+			return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "unexpected call"}
+		}
+		out, _ := hmysolidityio.EncodeAll(big.NewInt(1000000), big.NewInt(2000000), big.NewInt(0))
+		return "0x" + out
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	ratios, err := l.GetReserves([]types.LiquidityPool{pool}, 1000)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if len(ratios) != 1 || ratios[0].BlockNum != 999 || ratios[0].ReserveA.Int64() != 2000000 || ratios[0].ReserveB.Int64() != 1000000 {
+		t.Fatalf("Result did not contain correct reserves: %v", ratios)
+	}
+
+	// Fees of swapping 1000 X for 1990 Y. Without fees the swap would have returned 1998 Y
+	input, _ := hmysolidityio.EncodeAll(big.NewInt(1000), big.NewInt(0), []interface{}{tokenX.Address, tokenY.Address}, tokenX.Address, big.NewInt(0))
+	tx := types.Transaction{BlockNum: 1000, Input: "0x38ed1739" + input, Method: types.Method{Signature: "38ed1739"}}
+	swap := types.Swap{InToken: tokenX, OutToken: tokenY, InAmount: big.NewInt(1000), OutAmount: big.NewInt(1990), Path: []types.LiquidityPool{pool}}
+	err = l.AnalyzeFees(&swap, tx)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if swap.FeeToken != tokenY.Address.OneAddress || swap.FeeAmount.Int64() != 8 {
+		t.Errorf("AnalyzeFees returned %s %s instead of 8 %s", swap.FeeAmount, swap.FeeToken, tokenY.Address.OneAddress)
+	}
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Errorf("Reserves were loaded %d times instead of once", calls)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	t.Parallel()
+	var calls int64
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		atomic.AddInt64(&calls, 1)
+		return tokenHandler(method, params)
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	wone := types.Token{Address: types.NewAddress("0x6666666666666666666666666666666666666666")}
+	pool := types.LiquidityPool{TokenA: wone, TokenB: wone, LpToken: wone}
+	ttxs := []types.TokenTransaction{{Token: wone}, {Token: wone}}
+	swap := types.Swap{InToken: wone, OutToken: wone, Path: []types.LiquidityPool{pool}}
+	err = l.Enrich(ttxs, &swap)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	for _, tk := range []types.Token{ttxs[0].Token, ttxs[1].Token, swap.InToken, swap.OutToken, swap.Path[0].TokenA, swap.Path[0].LpToken} {
+		if tk.Name != "Wrapped ONE" || tk.Symbol != "WONE" || tk.Decimals != 18 {
+			t.Errorf("Result did contain incorrect token: %v", tk)
+		}
+	}
+	// Name, symbol and decimals of one token
+	if atomic.LoadInt64(&calls) != 3 {
+		t.Errorf("Enrich made %d instead of 3 calls", calls)
+	}
+	// Known tokens are not loaded again
+	err = l.Enrich(&pool)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if pool.LpToken.Symbol != "WONE" || atomic.LoadInt64(&calls) != 3 {
+		t.Errorf("Enrich did not reuse known token: %v after %d calls", pool.LpToken, calls)
+	}
+	if err = l.Enrich(wone); err == nil {
+		t.Errorf("Enrich accepted a token which is not a pointer")
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	t.Parallel()
+	var calls int64
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		atomic.AddInt64(&calls, 1)
+		return tokenHandler(method, params)
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	addr := types.NewAddress("0x8888888888888888888888888888888888888888")
+	for i := 0; i < 2; i++ {
+		tks, err := l.GetTokens(addr)
+		if err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+		if tks[0].Address != addr || tks[0].Symbol != "WONE" || tks[0].Decimals != 18 {
+			t.Errorf("Result did contain incorrect token: %v", tks[0])
+		}
+	}
+
+	if atomic.LoadInt64(&calls) != 3 {
+		t.Errorf("Token was loaded with %d instead of 3 calls", calls)
+	}
+	tks := centralCache.GetTokensByFilter(func(tk *types.Token) bool {
+		return tk.Address == addr
+	})
+	if len(tks) != 1 || tks[0].Name != "Wrapped ONE" {
+		t.Errorf("Cache did not contain loaded token: %v", tks)
+	}
+}
+
+func TestGetTokensNonStandard(t *testing.T) {
+	t.Parallel()
+	standard := types.NewAddress("0x9999999999999999999999999999999999999999")
+	bytes32 := types.NewAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	wallet := types.NewAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	noDecimals := types.NewAddress("0xcccccccccccccccccccccccccccccccccccccccc")
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		call := params[0].(map[string]interface{})
+		switch call["to"] {
+		case bytes32.HexAddress:
+			// Like MKR name and symbol are bytes32
+			switch call["data"] {
+			case "0x06fdde03":
+				return "0x" + hex.EncodeToString([]byte("Maker")) + strings.Repeat("0", 64-10)
+			case "0x95d89b41":
+				return "0x" + hex.EncodeToString([]byte("MKR")) + strings.Repeat("0", 64-6)
+			}
+		case wallet.HexAddress:
+			return "0x"
+		case noDecimals.HexAddress:
+			if call["data"] == "0x313ce567" {
+				return &rpc.Error{Code: rpc.ServerErrorCode, Message: "execution reverted"}
+			}
+		}
+		return tokenHandler(method, params)
+	})
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	tks, err := l.GetTokens(standard, bytes32, wallet, noDecimals)
+	var tkErrs hmyload.TokenErrors
+	if !errors.As(err, &tkErrs) {
+		t.Fatalf("GetTokens did not return TokenErrors: %v", err)
+	}
+
+	if tkErrs[0] != nil || tks[0].Symbol != "WONE" {
+		t.Errorf("Result did contain incorrect standard token: %v %v", tks[0], tkErrs[0])
+	}
+	if tkErrs[1] != nil || tks[1].Name != "Maker" || tks[1].Symbol != "MKR" || tks[1].Decimals != 18 {
+		t.Errorf("Result did contain incorrect bytes32 token: %v %v", tks[1], tkErrs[1])
+	}
+	for i := 2; i < 4; i++ {
+		if !errors.Is(tkErrs[i], hmyload.ErrNotToken) || tks[i].Address != []types.Address{wallet, noDecimals}[i-2] {
+			t.Errorf("Result did not mark address %d as not a token: %v %v", i, tks[i], tkErrs[i])
+		}
+	}
+}
+
+func TestLoaderSignatureResolver(t *testing.T) {
+	t.Parallel()
+	db, err := hmysolidityio.NewDatabaseResolver(strings.NewReader("abcdef02 custom(uint8,address)"))
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	n := echoNode()
+	defer n.Close()
+	l, err := hmyload.NewLoader(n.url, &hmyload.Opts{ExistingCache: centralCache, SignatureResolver: db})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer l.Close()
+	m, err := l.GetMethod("abcdef02")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if m.Name != "custom" || len(m.Parameters) != 2 || m.Parameters[1] != "address" {
+		t.Errorf("Result did contain incorrect method: %v", m)
+	}
+	// Methods of the bundled database are known offline
+	for _, sig := range []string{"38ed1739", "e8e33700", "a9059cbb", "d0e30db0"} {
+		if _, ok, _ := hmysolidityio.BundledResolver().ResolveMethod(context.Background(), sig); !ok {
+			t.Errorf("Bundled signatures do not contain %s", sig)
+		}
+	}
+}
@@ -1,12 +1,21 @@
 package test
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
 	"github.com/mjmar01/harmolytics/pkg/types"
+	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -55,6 +64,67 @@ func TestDecodeString(t *testing.T) {
 	}
 }
 
+func TestDecodeStringInvalid(t *testing.T) {
+	// Empty results of wallets and strings pointing outside the input
+	for _, in := range []string{"0x", testString[:64], testString[:128]} {
+		if _, err := hmysolidityio.DecodeString(in, 0); err == nil {
+			t.Errorf("Invalid input was decoded: %s", in)
+		}
+	}
+	s, err := hmysolidityio.DecodeString(testString[:64]+strings.Repeat("0", 64), 0)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if s != "" {
+		t.Errorf("Output did contain incorrect empty string: %s", s)
+	}
+	// Huge offsets and lengths must not wrap around
+	huge := strings.Repeat("f", 64)
+	for _, in := range []string{huge, testString[:64] + huge} {
+		if _, err := hmysolidityio.DecodeString(in, 0); err == nil {
+			t.Errorf("Invalid input was decoded: %s", in)
+		}
+	}
+}
+
+func TestDecodeArrayInvalid(t *testing.T) {
+	// Truncated results and arrays pointing outside the input
+	word := func(n int) string { return fmt.Sprintf("%064x", n) }
+	for _, in := range []string{"0x", word(32), word(64) + word(0), word(32) + word(2) + word(1), strings.Repeat("f", 64), word(32) + strings.Repeat("f", 64)} {
+		if _, err := hmysolidityio.DecodeArray(in, 0); err == nil {
+			t.Errorf("Invalid input was decoded: %s", in)
+		}
+	}
+	if _, err := hmysolidityio.DecodeArray(testSwapInput, 9); err == nil {
+		t.Errorf("Array after the end of the input was decoded")
+	}
+	arr, err := hmysolidityio.DecodeArray(word(32)+word(0), 0)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(arr) != 0 {
+		t.Errorf("Output slice has incorrect length: %d", len(arr))
+	}
+
+	for _, in := range []string{"0x", testString[:64]} {
+		if _, err := hmysolidityio.DecodeAddress(in, 1); err == nil {
+			t.Errorf("Address after the end of the input was decoded: %s", in)
+		}
+	}
+}
+
+func TestDecodeBytes32String(t *testing.T) {
+	s, err := hmysolidityio.DecodeBytes32String("0x"+testString[128:], 0)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	if s != "Hi mom!" {
+		t.Errorf("Output did contain incorrect string: %s", s)
+	}
+}
+
 func TestDecodeArray(t *testing.T) {
 	arr, err := hmysolidityio.DecodeArray(testSwapInput, 2)
 	if err != nil {
@@ -74,39 +144,351 @@ func TestDecodeArray(t *testing.T) {
 }
 
 func TestEncodeAll(t *testing.T) {
-	i := new(big.Int)
-	i.SetInt64(17)
 	addr := types.NewAddress("one1afvfayllrzc6ru0fhtr7705x4d32mhrewz4c77")
-	s, err := hmysolidityio.EncodeAll(
-		i,    // 0
-		addr, // 1
-		"Looooooooooooooooooooooooooooooooooooooooooooong test", // 2
-		"Short test", // 3
-		[]interface{}{ // 4
-			addr,
-			addr,
-		},
-		[]interface{}{ // 5
-			[]interface{}{
-				"I love recursion!",
-				"Inception!!!",
-			},
-		},
-		"~FIN~", // 6
-	)
-	if err != nil {
-		t.Fatal(err.(*errors.Error).ErrorStack())
-	}
-
-	if s != "0000000000000000000000000000000000000000000000000000000000000011000000000000000000000000ea589e93ff18b1a1f1e9bac7ef3e86ab62addc7900000000000000000000000000000000000000000000000000000000000000e00000000000000000000000000000000000000000000000000000000000000140000000000000000000000000000000000000000000000000000000000000018000000000000000000000000000000000000000000000000000000000000001e0000000000000000000000000000000000000000000000000000000000000022000000000000000000000000000000000000000000000000000000000000000354c6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6f6e6720746573740000000000000000000000000000000000000000000000000000000000000000000000000000000000000a53686f72742074657374000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000000000000000000000ea589e93ff18b1a1f1e9bac7ef3e86ab62addc79000000000000000000000000ea589e93ff18b1a1f1e9bac7ef3e86ab62addc790000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000026000000000000000000000000000000000000000000000000000000000000000057e46494e7e000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000002c00000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000001149206c6f766520726563757273696f6e21000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000c496e63657074696f6e2121210000000000000000000000000000000000000000" {
-		readHelper := ""
-		for {
-			readHelper += fmt.Sprintf("%s\n", s[:64])
-			s = s[64:]
-			if len(s) == 0 {
-				break
+	ethAddr := common.HexToAddress(addr.HexAddress)
+	tests := []struct {
+		name string
+		in   []interface{}
+		// Solidity types and go-ethereum values of the reference encoding
+		types []string
+		ref   []interface{}
+	}{
+		{"static", []interface{}{big.NewInt(17), addr, true, -3},
+			[]string{"uint256", "address", "bool", "int256"}, []interface{}{big.NewInt(17), ethAddr, true, big.NewInt(-3)}},
+		{"bytes", []interface{}{[]byte{1}, []byte{2}},
+			[]string{"bytes", "bytes"}, []interface{}{[]byte{1}, []byte{2}}},
+		{"strings", []interface{}{"a", "b"},
+			[]string{"string", "string"}, []interface{}{"a", "b"}},
+		{"fixed bytes", []interface{}{[4]byte{0xca, 0xfe}, [32]byte{1}},
+			[]string{"bytes4", "bytes32"}, []interface{}{[4]byte{0xca, 0xfe}, [32]byte{1}}},
+		{"fixed arrays", []interface{}{[2]*big.Int{big.NewInt(1), big.NewInt(-1)}, [2]string{"x", "y"}},
+			[]string{"int256[2]", "string[2]"}, []interface{}{[2]*big.Int{big.NewInt(1), big.NewInt(-1)}, [2]string{"x", "y"}}},
+		{"nested dynamic", []interface{}{
+			big.NewInt(17),
+			"Looooooooooooooooooooooooooooooooooooooooooooong test",
+			[]interface{}{addr, addr},
+			[]interface{}{[]interface{}{"I love recursion!", "Inception!!!"}},
+			[][]byte{{1, 2}, {3}},
+			"~FIN~",
+		}, []string{"uint256", "string", "address[]", "string[][]", "bytes[]", "string"}, []interface{}{
+			big.NewInt(17),
+			"Looooooooooooooooooooooooooooooooooooooooooooong test",
+			[]common.Address{ethAddr, ethAddr},
+			[][]string{{"I love recursion!", "Inception!!!"}},
+			[][]byte{{1, 2}, {3}},
+			"~FIN~",
+		}},
+		{"empty", []interface{}{[]interface{}{}, ""},
+			[]string{"uint256[]", "string"}, []interface{}{[]*big.Int{}, ""}},
+	}
+	for _, test := range tests {
+		s, err := hmysolidityio.EncodeAll(test.in...)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err.(*errors.Error).ErrorStack())
+		}
+		var args abi.Arguments
+		for _, name := range test.types {
+			typ, err := abi.NewType(name, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args = append(args, abi.Argument{Type: typ})
+		}
+		packed, err := args.Pack(test.ref...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != hex.EncodeToString(packed) {
+			t.Errorf("%s: encoding differs from reference:\n%s\n%s", test.name, s, hex.EncodeToString(packed))
+		}
+	}
+
+	_, err := hmysolidityio.EncodeAll([]interface{}{"a", 1})
+	if err == nil {
+		t.Errorf("Array of mixed types was encoded")
+	}
+	_, err = hmysolidityio.EncodeAll(nil)
+	if err == nil {
+		t.Errorf("nil was encoded")
+	}
+}
+
+func TestSignatureResolvers(t *testing.T) {
+	t.Parallel()
+	// Local stand-in for 4byte.directory
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if req.URL.Query().Get("hex_signature") == "0x99999999" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"results":[]}`)
+			return
+		}
+		if req.URL.Query().Get("hex_signature") != "0x12345678" {
+			fmt.Fprint(w, `{"results":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"id":2,"text_signature":"collision(bytes)"},{"id":1,"text_signature":"stake(uint256,address)"}]}`)
+	}))
+	defer server.Close()
+	fourByte := hmysolidityio.NewFourByteResolver(server.URL + "/api/v1/signatures/?hex_signature=0x")
+	// ABI with a tuple parameter
+	abiPath := filepath.Join(t.TempDir(), "abi.json")
+	err := ioutil.WriteFile(abiPath, []byte(`[
+		{"type":"event","name":"Transfer","inputs":[{"type":"address"}]},
+		{"type":"function","name":"exactInput","inputs":[{"type":"tuple","components":[{"type":"bytes"},{"type":"address"},{"type":"uint256"},{"type":"uint256"},{"type":"uint256"}]}]}
+	]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	abiResolver, err := hmysolidityio.NewAbiResolver(abiPath)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	db, err := hmysolidityio.NewDatabaseResolver(strings.NewReader("# Custom methods\nabcdef01 custom(uint8)\n"))
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	chain := hmysolidityio.ChainResolver{hmysolidityio.NewFourByteResolver(closed.URL + "/"), hmysolidityio.BundledResolver(), db, abiResolver, fourByte}
+
+	for sig, name := range map[string]string{
+		"a9059cbb": "transfer",
+		"abcdef01": "custom",
+		"12345678": "stake",
+		// Uniswap V3 router exactInput((bytes,address,uint256,uint256,uint256))
+		"c04b8d59": "exactInput",
+	} {
+		m, ok, err := chain.ResolveMethod(context.Background(), sig)
+		if err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+		if !ok || m.Name != name || m.Signature != sig {
+			t.Errorf("Chain did not resolve %s to %s: %v", sig, name, m)
+		}
+	}
+	_, ok, err := chain.ResolveMethod(context.Background(), "00000000")
+	if ok || err == nil {
+		t.Errorf("Chain did not return the error of the unreachable resolver for an unknown signature")
+	}
+	// Only 12345678 and 00000000 were unknown to the local resolvers
+	if atomic.LoadInt64(&requests) != 2 {
+		t.Errorf("4byte stand-in received %d instead of 2 requests", requests)
+	}
+	// Rate limited lookups are errors, not unknown signatures
+	_, ok, err = fourByte.ResolveMethod(context.Background(), "99999999")
+	if ok || err == nil {
+		t.Errorf("4byte resolver did not fail on a rate limited request")
+	}
+}
+
+type testAbiHop struct {
+	Token  types.Address `abi:"address"`
+	Amount *big.Int      `abi:"uint256"`
+}
+
+type testAbiStruct struct {
+	Flag    bool            `abi:"bool"`
+	Delta   int64           `abi:"int8"`
+	Hash    [32]byte        `abi:"bytes32"`
+	Data    []byte          `abi:"bytes"`
+	Pair    [2]*big.Int     `abi:"uint256[2]"`
+	Route   testAbiHop      `abi:"tuple"`
+	Hops    []testAbiHop    `abi:"tuple[]"`
+	Wallets []types.Address `abi:"address[]"`
+	Note    string          `abi:"string"`
+	Ignored int
+}
+
+func TestStructCodec(t *testing.T) {
+	token := types.NewAddress("0x44ed4df2a04a8ddb08ea7d12b8e6e30b2c0f6ba3")
+	wallet := types.NewAddress("0xea589e93ff18b1a1f1e9bac7ef3e86ab62addc79")
+	in := testAbiStruct{
+		Flag:    true,
+		Delta:   -5,
+		Hash:    [32]byte{1, 2, 3},
+		Data:    []byte("some bytes longer than a single word of thirty-two"),
+		Pair:    [2]*big.Int{big.NewInt(7), big.NewInt(8)},
+		Route:   testAbiHop{Token: token, Amount: big.NewInt(100)},
+		Hops:    []testAbiHop{{Token: wallet, Amount: big.NewInt(1)}, {Token: token, Amount: big.NewInt(2)}},
+		Wallets: []types.Address{wallet, token},
+		Note:    "Hi mom!",
+		Ignored: 42,
+	}
+	s, err := hmysolidityio.EncodeStruct(in)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+
+	// Compare against the reference implementation
+	type hop struct {
+		Token  common.Address
+		Amount *big.Int
+	}
+	hopType, err := abi.NewType("tuple", "", []abi.ArgumentMarshaling{{Name: "token", Type: "address"}, {Name: "amount", Type: "uint256"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hopsType, err := abi.NewType("tuple[]", "", []abi.ArgumentMarshaling{{Name: "token", Type: "address"}, {Name: "amount", Type: "uint256"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args abi.Arguments
+	for _, name := range []string{"bool", "int8", "bytes32", "bytes", "uint256[2]", "", "", "address[]", "string"} {
+		typ := hopType
+		if name != "" {
+			typ, err = abi.NewType(name, "", nil)
+			if err != nil {
+				t.Fatal(err)
 			}
+		} else if len(args) == 6 {
+			typ = hopsType
+		}
+		args = append(args, abi.Argument{Type: typ})
+	}
+	packed, err := args.Pack(true, int8(-5), in.Hash, in.Data, [2]*big.Int{big.NewInt(7), big.NewInt(8)},
+		hop{common.HexToAddress(token.HexAddress), big.NewInt(100)},
+		[]hop{{common.HexToAddress(wallet.HexAddress), big.NewInt(1)}, {common.HexToAddress(token.HexAddress), big.NewInt(2)}},
+		[]common.Address{common.HexToAddress(wallet.HexAddress), common.HexToAddress(token.HexAddress)}, "Hi mom!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != hex.EncodeToString(packed) {
+		t.Errorf("Encoding differs from reference:\n%s\n%s", s, hex.EncodeToString(packed))
+	}
+
+	var out testAbiStruct
+	err = hmysolidityio.DecodeStruct("0x12345678"+s, &out)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	in.Ignored = 0
+	if fmt.Sprint(out) != fmt.Sprint(in) {
+		t.Errorf("Decoded struct differs:\n%v\n%v", out, in)
+	}
+
+	err = hmysolidityio.DecodeStruct(s[:len(s)-64], &out)
+	if err == nil {
+		t.Errorf("Truncated input was decoded")
+	}
+	_, err = hmysolidityio.EncodeStruct(struct {
+		N int `abi:"uint8"`
+	}{256})
+	if err == nil {
+		t.Errorf("Overflowing integer was encoded")
+	}
+}
+
+func TestDecodeAbiTypes(t *testing.T) {
+	s, err := hmysolidityio.EncodeAll(true, big.NewInt(-2), []byte{0xca, 0xfe})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	b, err := hmysolidityio.DecodeBool(s, 0)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if !b {
+		t.Errorf("Expected true")
+	}
+	n, err := hmysolidityio.DecodeSignedInt(s, 1)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if n.Int64() != -2 {
+		t.Errorf("Expected -2 but got %s", n)
+	}
+	bs, err := hmysolidityio.DecodeBytes(s, 2)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if hex.EncodeToString(bs) != "cafe" {
+		t.Errorf("Expected cafe but got %x", bs)
+	}
+	fixed, err := hmysolidityio.DecodeFixedBytes(testSwapInput, 3, 20)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if hex.EncodeToString(fixed) != "000000000000000000000000a33f8390105ecbdf" {
+		t.Errorf("Unexpected bytes20 %x", fixed)
+	}
+	_, err = hmysolidityio.DecodeBool(testSwapInput, 0)
+	if err == nil {
+		t.Errorf("42 was decoded as bool")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// swapTokensForExactTokens(uint256,uint256,address[],address,uint256)
+	values, err := hmysolidityio.Decode("(uint256,uint256,address[],address,uint256)", testSwapInput)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(values) != 5 {
+		t.Fatalf("Expected 5 values but got %d", len(values))
+	}
+	if values[0].(*big.Int).Int64() != 42 || values[4].(*big.Int).Int64() != 0x6081cd0f {
+		t.Errorf("Unexpected amounts %v", values)
+	}
+	path := values[2].([]interface{})
+	if len(path) != 2 || path[1].(types.Address).HexAddress != "0x58f1b044d8308812881a1433d9bbeff99975e70c" {
+		t.Errorf("Unexpected path %v", path)
+	}
+	if values[3].(types.Address).OneAddress != "one15vlc8yqstm9algcf6e94dxqx6y04jcsqjuc3gt" {
+		t.Errorf("Unexpected recipient %v", values[3])
+	}
+
+	// Tuple parameters are kept in one piece
+	db, err := hmysolidityio.NewDatabaseResolver(strings.NewReader("c04b8d59 exactInput((bytes,address,uint256,uint256,uint256))"))
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	m, _, err := db.ResolveMethod(context.Background(), "c04b8d59")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if len(m.Parameters) != 1 {
+		t.Fatalf("Expected a single tuple parameter but got %v", m.Parameters)
+	}
+	type exactInputParams struct {
+		Path      []byte        `abi:"bytes"`
+		Recipient types.Address `abi:"address"`
+		Deadline  uint64        `abi:"uint256"`
+		AmountIn  *big.Int      `abi:"uint256"`
+		AmountOut *big.Int      `abi:"uint256"`
+	}
+	input, err := hmysolidityio.EncodeStruct(struct {
+		Params exactInputParams `abi:"tuple"`
+	}{exactInputParams{[]byte{1, 2, 3}, types.NewAddress("0xea589e93ff18b1a1f1e9bac7ef3e86ab62addc79"), 1700000000, big.NewInt(5), big.NewInt(1)}})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	values, err = hmysolidityio.DecodeMethodInput(m, "0xc04b8d59"+input)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	params := values[0].([]interface{})
+	if hex.EncodeToString(params[0].([]byte)) != "010203" || params[2].(*big.Int).Int64() != 1700000000 {
+		t.Errorf("Unexpected params %v", params)
+	}
+
+	_, err = hmysolidityio.Decode("(uint256,string)", testSwapInput)
+	if err == nil {
+		t.Errorf("Out of range string offset was decoded")
+	}
+	_, err = hmysolidityio.Decode("(uint7)", testSwapInput)
+	if err == nil {
+		t.Errorf("Invalid type was accepted")
+	}
+	_, err = hmysolidityio.Decode("uint256[4294967295]", testString[:64])
+	if err == nil {
+		t.Errorf("Fixed array larger than the input was decoded")
+	}
+	for _, short := range []string{"0x1", "0x12345", "abc"} {
+		_, err = hmysolidityio.Decode("uint256", short)
+		if err == nil {
+			t.Errorf("Malformed input %s was decoded", short)
 		}
-		t.Errorf("Output string is incorrect:\n%s", readHelper)
 	}
 }
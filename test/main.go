@@ -3,10 +3,8 @@ package test
 import (
 	"bytes"
 	"encoding/gob"
-	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/cache"
 	"github.com/mjmar01/harmolytics/pkg/hmybebop"
-	"github.com/mjmar01/harmolytics/pkg/rpc"
 	"github.com/mjmar01/harmolytics/pkg/types"
 	"math/big"
 )
@@ -47,7 +45,6 @@ var tx = &types.Transaction{
 var txBebop, txGob []byte
 
 var centralCache *cache.Cache
-var defaultRPC *rpc.RPC
 
 var dump interface{}
 
@@ -58,17 +55,4 @@ func init() {
 	enc.Encode(tx)
 	txGob = buff.Bytes()
 	txBebop, _ = hmybebop.EncodeTransaction(tx)
-
-	// Open central cache
-	var err error
-	centralCache, err = cache.NewCache(&cache.Opts{PreLoadTransactions: false})
-	if err != nil {
-		panic(err.(*errors.Error).ErrorStack())
-	}
-
-	// Open benchmark RPC
-	defaultRPC, err = rpc.NewRPC(url, nil)
-	if err != nil {
-		panic(err.(*errors.Error).ErrorStack())
-	}
 }
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-errors/errors"
+	"github.com/mjmar01/harmolytics/pkg/cache"
+	"github.com/mjmar01/harmolytics/pkg/hmyload"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const (
+	fixturePath = "testdata/mainnet.json"
+)
+
+var online = flag.Bool("online", false, "run tests against "+url+" and record the fixtures used by offline runs")
+
+// fixtureClient serves mainnet data, either recorded from or replayed to the tests
+var fixtureClient rpc.Client
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// Open central cache. Always start empty so every run requests the same data
+	cacheDir, err := ioutil.TempDir("", "harmolytics-test")
+	if err != nil {
+		panic(err)
+	}
+	centralCache, err = cache.NewCache(&cache.Opts{CacheDir: cacheDir, PreLoadTransactions: false})
+	if err != nil {
+		panic(err.(*errors.Error).ErrorStack())
+	}
+
+	// Open fixture client
+	var recorder *rpc.Recorder
+	if *online {
+		r, err := rpc.NewRPC(url, nil)
+		if err != nil {
+			panic(err.(*errors.Error).ErrorStack())
+		}
+		recorder = rpc.NewRecorder(r, fixturePath)
+		fixtureClient = recorder
+	} else {
+		fixtureClient, err = rpc.NewReplayClient(fixturePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fixtures at %s can't be replayed, record them by running with -online: %s\n", fixturePath, err)
+			os.Exit(1)
+		}
+	}
+
+	code := m.Run()
+	if recorder != nil {
+		if err = recorder.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "fixtures could not be saved to %s: %s\n", fixturePath, err)
+			code = 1
+		}
+	}
+	fixtureClient.Close()
+	centralCache.Close()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
+// fixtureLoader returns a Loader serving mainnet data from fixtures or from mainnet when running -online
+func fixtureLoader(t *testing.T) *hmyload.Loader {
+	l, err := hmyload.NewLoader(url, &hmyload.Opts{ExistingCache: centralCache, ExistingClients: []rpc.Client{fixtureClient}})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	return l
+}
+
+// mainnetUrl returns the url of a local stand-in for mainnet serving mainnetHandler, or mainnet itself when running -online
+func mainnetUrl(t *testing.T) string {
+	if *online {
+		return url
+	}
+	n := newLocalNode(mainnetHandler)
+	t.Cleanup(n.Close)
+	return n.url
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/mjmar01/harmolytics/pkg/hmysolidityio"
+	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// nodeHandler answers a single JSON-RPC request of a localNode. Returning an *rpc.Error replies with an error object
+type nodeHandler func(method string, params []interface{}) (result interface{})
+
+// localNode is a stand-in for a harmony node serving JSON-RPC over a local websocket.
+// Replies are written from separate go routines after a random delay, so they arrive out of order.
+type localNode struct {
+	server *httptest.Server
+	url    string
+	handle nodeHandler
+	// dropEvery closes the websocket after every n-th reply, replies still in flight are lost
+	dropEvery int64
+	replies   int64
+	// Open websockets to push notifications to
+	conns     map[*websocket.Conn]*sync.Mutex
+	connMutex sync.Mutex
+}
+
+type nodeRequest struct {
+	Id     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+}
+
+func newLocalNode(handle nodeHandler) (n *localNode) {
+	n = &localNode{handle: handle, conns: map[*websocket.Conn]*sync.Mutex{}}
+	upgrader := websocket.Upgrader{}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ws, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		writeMutex := sync.Mutex{}
+		n.connMutex.Lock()
+		n.conns[ws] = &writeMutex
+		n.connMutex.Unlock()
+		defer func() {
+			n.connMutex.Lock()
+			delete(n.conns, ws)
+			n.connMutex.Unlock()
+		}()
+		for {
+			var rq nodeRequest
+			err = ws.ReadJSON(&rq)
+			if err != nil {
+				return
+			}
+			go func(rq nodeRequest) {
+				time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+				rpl := n.replyJson(rq)
+				writeMutex.Lock()
+				ws.WriteJSON(rpl)
+				if n.dropEvery > 0 && atomic.AddInt64(&n.replies, 1)%n.dropEvery == 0 {
+					ws.Close()
+				}
+				writeMutex.Unlock()
+			}(rq)
+		}
+	}))
+	n.url = "ws" + strings.TrimPrefix(n.server.URL, "http")
+	return
+}
+
+// newLocalHttpNode serves JSON-RPC batch arrays over plain HTTP. Replies of a batch are shuffled
+func newLocalHttpNode(handle nodeHandler) (n *localNode) {
+	n = &localNode{handle: handle}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var rqs []nodeRequest
+		err := json.NewDecoder(req.Body).Decode(&rqs)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rpls := make([]map[string]interface{}, len(rqs))
+		for i, rq := range rqs {
+			rpls[i] = n.replyJson(rq)
+		}
+		rand.Shuffle(len(rpls), func(i, j int) {
+			rpls[i], rpls[j] = rpls[j], rpls[i]
+		})
+		json.NewEncoder(w).Encode(rpls)
+	}))
+	n.url = n.server.URL
+	return
+}
+
+func (n *localNode) replyJson(rq nodeRequest) (rpl map[string]interface{}) {
+	rpl = map[string]interface{}{"jsonrpc": "2.0", "id": rq.Id}
+	res := n.reply(rq)
+	if rpcErr, ok := res.(*rpc.Error); ok {
+		rpl["error"] = rpcErr
+	} else {
+		rpl["result"] = res
+	}
+	return
+}
+
+func (n *localNode) reply(rq nodeRequest) interface{} {
+	if rq.Method == "hmyv2_getNodeMetadata" {
+		return map[string]interface{}{"peerid": "local"}
+	}
+	return n.handle(rq.Method, rq.Params)
+}
+
+// notify pushes an eth_subscription notification to every open websocket
+func (n *localNode) notify(subscription string, result interface{}) {
+	n.connMutex.Lock()
+	defer n.connMutex.Unlock()
+	for ws, writeMutex := range n.conns {
+		writeMutex.Lock()
+		ws.WriteJSON(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"method":  "eth_subscription",
+			"params":  map[string]interface{}{"subscription": subscription, "result": result},
+		})
+		writeMutex.Unlock()
+	}
+}
+
+// drop closes every open websocket
+func (n *localNode) drop() {
+	n.connMutex.Lock()
+	defer n.connMutex.Unlock()
+	for ws, writeMutex := range n.conns {
+		writeMutex.Lock()
+		ws.Close()
+		writeMutex.Unlock()
+	}
+}
+
+func (n *localNode) Close() {
+	n.server.Close()
+}
+
+// echoNode returns the first parameter of every request
+func echoNode() *localNode {
+	return newLocalNode(func(method string, params []interface{}) interface{} {
+		if len(params) == 0 {
+			return nil
+		}
+		return params[0]
+	})
+}
+
+// tokenHandler answers hmyv2_call for name, symbol and decimals as any HRC-20 token named "Wrapped ONE" would
+func tokenHandler(method string, params []interface{}) interface{} {
+	if method != "hmyv2_call" {
+		return &rpc.Error{Code: rpc.MethodNotFoundCode, Message: "method not found"}
+	}
+	var out string
+	switch params[0].(map[string]interface{})["data"] {
+	case "0x06fdde03":
+		out, _ = hmysolidityio.EncodeAll("Wrapped ONE")
+	case "0x95d89b41":
+		out, _ = hmysolidityio.EncodeAll("WONE")
+	case "0x313ce567":
+		out, _ = hmysolidityio.EncodeAll(big.NewInt(18))
+	default:
+		return &rpc.Error{Code: rpc.ServerErrorCode, Message: "execution reverted"}
+	}
+	return "0x" + out
+}
+
+// mainnetHandler answers the requests of the rpc tests with the values mainnet returns for them
+func mainnetHandler(method string, params []interface{}) interface{} {
+	switch method {
+	case "hmyv2_getTransactionByHash":
+		return map[string]interface{}{"hash": params[0], "from": "one1a5fznwvnr3fed9676g42u7q30crtmmkk5qspe9"}
+	case "hmyv2_getTransactionReceipt":
+		return map[string]interface{}{"transactionHash": params[0]}
+	case "hmyv2_blockNumber":
+		return 23000000
+	case "hmyv2_getTransactionsHistory":
+		// Pages are full enough for the history benchmark to find 20000 transactions
+		hashes := make([]string, 251)
+		for i := range hashes {
+			hashes[i] = fmt.Sprintf("0x%064x", i)
+		}
+		return map[string]interface{}{"transactions": hashes}
+	}
+	return tokenHandler(method, params)
+}
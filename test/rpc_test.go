@@ -1,11 +1,15 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-errors/errors"
 	"github.com/mjmar01/harmolytics/pkg/rpc"
+	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -18,23 +22,24 @@ const (
 
 func TestNewRpc(t *testing.T) {
 	t.Parallel()
-	r, err := rpc.NewRPC(url, nil)
+	nodeUrl := mainnetUrl(t)
+	r, err := rpc.NewRPC(nodeUrl, nil)
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
 	}
 	r.Close()
-	r, err = rpc.NewRPC(url, &rpc.Opts{Timeout: time.Minute * 3})
+	r, err = rpc.NewRPC(nodeUrl, &rpc.Opts{Timeout: time.Minute * 3})
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
 	}
 	r.Close()
-	rs, err := rpc.NewRPCs(url, 2, nil)
+	rs, err := rpc.NewRPCs(nodeUrl, 2, nil)
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
 	}
 	rs[0].Close()
 	rs[1].Close()
-	rs, err = rpc.NewRPCs(url, 2, &rpc.Opts{Timeout: time.Minute * 3})
+	rs, err = rpc.NewRPCs(nodeUrl, 2, &rpc.Opts{Timeout: time.Minute * 3})
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
 	}
@@ -44,7 +49,8 @@ func TestNewRpc(t *testing.T) {
 
 func TestCall(t *testing.T) {
 	t.Parallel()
-	r, err := rpc.NewRPC(url, nil)
+	nodeUrl := mainnetUrl(t)
+	r, err := rpc.NewRPC(nodeUrl, nil)
 	defer r.Close()
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -62,7 +68,8 @@ func TestCall(t *testing.T) {
 
 func TestBatchCall(t *testing.T) {
 	t.Parallel()
-	r, err := rpc.NewRPC(url, nil)
+	nodeUrl := mainnetUrl(t)
+	r, err := rpc.NewRPC(nodeUrl, nil)
 	defer r.Close()
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -92,7 +99,8 @@ func TestBatchCall(t *testing.T) {
 
 func TestRawCall(t *testing.T) {
 	t.Parallel()
-	r, err := rpc.NewRPC(url, nil)
+	nodeUrl := mainnetUrl(t)
+	r, err := rpc.NewRPC(nodeUrl, nil)
 	defer r.Close()
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -110,7 +118,8 @@ func TestRawCall(t *testing.T) {
 
 func TestRawBatchCall(t *testing.T) {
 	t.Parallel()
-	r, err := rpc.NewRPC(url, nil)
+	nodeUrl := mainnetUrl(t)
+	r, err := rpc.NewRPC(nodeUrl, nil)
 	defer r.Close()
 	if err != nil {
 		t.Fatal(err.(*errors.Error).ErrorStack())
@@ -141,7 +150,8 @@ func TestRawBatchCall(t *testing.T) {
 
 func TestBatches(t *testing.T) {
 	t.Parallel()
-	rs, err := rpc.NewRPCs(url, 10, nil)
+	nodeUrl := mainnetUrl(t)
+	rs, err := rpc.NewRPCs(nodeUrl, 10, nil)
 	defer func() {
 		for _, r := range rs {
 			r.Close()
@@ -169,3 +179,372 @@ func TestBatches(t *testing.T) {
 		}
 	}
 }
+
+func TestSharedRPC(t *testing.T) {
+	t.Parallel()
+	n := echoNode()
+	defer n.Close()
+	r, err := rpc.NewRPC(n.url, nil)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+	wg := sync.WaitGroup{}
+	wg.Add(20)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			defer wg.Done()
+			res, err := r.Call("echo", float64(i))
+			if err != nil {
+				t.Error(err.(*errors.Error).ErrorStack())
+				return
+			}
+			if res != float64(i) {
+				t.Errorf("Call %d received reply of another caller: %v", i, res)
+			}
+			bodies := make([]rpc.Body, 10)
+			for j := range bodies {
+				bodies[j] = r.NewBody("echo", fmt.Sprintf("%d-%d", i, j))
+			}
+			ress, err := r.RawBatchCall(bodies)
+			if err != nil {
+				t.Error(err.(*errors.Error).ErrorStack())
+				return
+			}
+			for j, res := range ress {
+				if string(res) != fmt.Sprintf("\"%d-%d\"", i, j) {
+					t.Errorf("BatchCall %d received reply %s at position %d", i, res, j)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRpcError(t *testing.T) {
+	t.Parallel()
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		if method == "fail" {
+			return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "invalid params"}
+		}
+		return "ok"
+	})
+	defer n.Close()
+	r, err := rpc.NewRPC(n.url, nil)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+
+	_, err = r.Call("fail")
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpc.InvalidParamsCode {
+		t.Errorf("Call did not return rpc.Error: %v", err)
+	}
+	_, err = r.RawCall("fail")
+	if !errors.As(err, &rpcErr) {
+		t.Errorf("RawCall did not return rpc.Error: %v", err)
+	}
+	ress, err := r.BatchCall([]rpc.Body{r.NewBody("ok"), r.NewBody("fail"), r.NewBody("ok")})
+	var batchErr rpc.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchCall did not return rpc.BatchError: %v", err)
+	}
+	if batchErr[0] != nil || batchErr[1] == nil || batchErr[2] != nil {
+		t.Errorf("BatchError does not match failed calls: %v", batchErr)
+	}
+	if ress[0] != "ok" || ress[2] != "ok" {
+		t.Errorf("BatchCall did not return successful results: %v", ress)
+	}
+	raws, err := r.RawBatchCall([]rpc.Body{r.NewBody("fail"), r.NewBody("ok")})
+	if !errors.As(err, &batchErr) || batchErr[0] == nil {
+		t.Errorf("RawBatchCall did not return rpc.BatchError: %v", err)
+	}
+	if string(raws[1]) != "\"ok\"" {
+		t.Errorf("RawBatchCall did not return successful results: %s", raws[1])
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	t.Parallel()
+	n := echoNode()
+	defer n.Close()
+	n.dropEvery = 7
+	r, err := rpc.NewRPC(n.url, &rpc.Opts{
+		Timeout: time.Second,
+		Retry:   &rpc.RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond * 10},
+	})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+	bodies := make([]rpc.Body, 50)
+	for i := range bodies {
+		bodies[i] = r.NewBody("echo", float64(i))
+	}
+	ress, err := r.BatchCall(bodies)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	for i, res := range ress {
+		if res != float64(i) {
+			t.Errorf("BatchCall returned incorrect result at position %d: %v", i, res)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		res, err := r.Call("echo", "again")
+		if err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+		if res != "again" {
+			t.Errorf("Call returned incorrect result: %v", res)
+		}
+	}
+}
+
+func TestNoReconnect(t *testing.T) {
+	t.Parallel()
+	n := echoNode()
+	defer n.Close()
+	n.dropEvery = 2
+	r, err := rpc.NewRPC(n.url, &rpc.Opts{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+	// The node drops the connection after this reply
+	_, err = r.Call("echo", "last")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	_, err = r.Call("echo", "dropped")
+	if err == nil {
+		t.Errorf("Call succeeded on a dropped connection without retry policy")
+	}
+}
+
+func TestRetryRateLimit(t *testing.T) {
+	t.Parallel()
+	var calls int64
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		if atomic.AddInt64(&calls, 1) <= 3 {
+			return &rpc.Error{Code: rpc.LimitExceededCode, Message: "rate limited"}
+		}
+		return "ok"
+	})
+	defer n.Close()
+	r, err := rpc.NewRPC(n.url, &rpc.Opts{Retry: &rpc.RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+	res, err := r.Call("limited")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if res != "ok" {
+		t.Errorf("Call returned incorrect result: %v", res)
+	}
+}
+
+func TestHttpRPC(t *testing.T) {
+	t.Parallel()
+	n := newLocalHttpNode(func(method string, params []interface{}) interface{} {
+		if method == "fail" {
+			return &rpc.Error{Code: rpc.MethodNotFoundCode, Message: "method not found"}
+		}
+		return params[0]
+	})
+	defer n.Close()
+	r, err := rpc.NewRPC(n.url, &rpc.Opts{BatchSize: 7})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+
+	res, err := r.Call("echo", "single")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if res != "single" {
+		t.Errorf("Call returned incorrect result: %v", res)
+	}
+	bodies := make([]rpc.Body, 30)
+	for i := range bodies {
+		bodies[i] = r.NewBody("echo", float64(i))
+	}
+	ress, err := r.BatchCall(bodies)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	for i, res := range ress {
+		if res != float64(i) {
+			t.Errorf("BatchCall returned incorrect result at position %d: %v", i, res)
+		}
+	}
+	raws, err := r.RawBatchCall([]rpc.Body{r.NewBody("echo", "raw"), r.NewBody("fail")})
+	var batchErr rpc.BatchError
+	if !errors.As(err, &batchErr) || batchErr[1] == nil {
+		t.Errorf("RawBatchCall did not return rpc.BatchError: %v", err)
+	}
+	if string(raws[0]) != "\"raw\"" {
+		t.Errorf("RawBatchCall returned incorrect result: %s", raws[0])
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		if method == "fail" {
+			return &rpc.Error{Code: rpc.InvalidParamsCode, Message: "invalid params"}
+		}
+		return params
+	})
+	defer n.Close()
+	r, err := rpc.NewRPC(n.url, nil)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	rec := rpc.NewRecorder(r, path)
+	recorded, err := rec.RawCall("echo", map[string]interface{}{"address": "one1", "pageSize": 50}, "latest")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	_, err = rec.BatchCall([]rpc.Body{rec.NewBody("echo", 1), rec.NewBody("fail", 2)})
+	if err == nil {
+		t.Fatal("BatchCall did not return the recorded error")
+	}
+	err = rec.Save()
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	// Fixtures below a file can't be written
+	err = rpc.NewRecorder(r, filepath.Join(path, "fixture.json")).Save()
+	if err == nil {
+		t.Errorf("Save did not fail for an unwritable path")
+	}
+	rec.Close()
+
+	c, err := rpc.NewReplayClient(path)
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if c.PeerId() != "local" {
+		t.Errorf("Replay client did not keep the peer ID: %s", c.PeerId())
+	}
+	replayed, err := c.RawCall("echo", map[string]interface{}{"pageSize": 50, "address": "one1"}, "latest")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	if string(replayed) != string(recorded) {
+		t.Errorf("Replayed result differs from recorded: %s|%s", replayed, recorded)
+	}
+	ress, err := c.BatchCall([]rpc.Body{c.NewBody("echo", 1), c.NewBody("fail", 2)})
+	var batchErr rpc.BatchError
+	if !errors.As(err, &batchErr) || batchErr[0] != nil || batchErr[1].Code != rpc.InvalidParamsCode {
+		t.Errorf("Replayed BatchCall did not return recorded errors: %v", err)
+	}
+	if ress[0].([]interface{})[0] != float64(1) {
+		t.Errorf("Replayed BatchCall did not return recorded result: %v", ress[0])
+	}
+	_, err = c.Call("unknown")
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpc.MethodNotFoundCode {
+		t.Errorf("Replay client did not fail unknown call: %v", err)
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	handle := func(method string, params []interface{}) interface{} {
+		if method == "block" {
+			<-release
+		}
+		return "ok"
+	}
+	wsNode, httpNode := newLocalNode(handle), newLocalHttpNode(handle)
+	defer wsNode.Close()
+	defer httpNode.Close()
+	defer close(release)
+	for _, n := range []*localNode{wsNode, httpNode} {
+		r, err := rpc.NewRPC(n.url, nil)
+		if err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err = r.BatchCallContext(ctx, []rpc.Body{r.NewBody("block"), r.NewBody("echo")})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("BatchCallContext did not stop at the deadline: %v", err)
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		_, err = r.CallContext(ctx, "echo")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("CallContext did not return context.Canceled: %v", err)
+		}
+		// The RPC stays usable after cancelled calls
+		res, err := r.Call("echo")
+		if err != nil {
+			t.Fatal(err.(*errors.Error).ErrorStack())
+		}
+		if res != "ok" {
+			t.Errorf("Call returned incorrect result: %v", res)
+		}
+		r.Close()
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+	var subscribes int64
+	n := newLocalNode(func(method string, params []interface{}) interface{} {
+		switch method {
+		case "eth_subscribe":
+			atomic.AddInt64(&subscribes, 1)
+			return "0x1"
+		case "eth_unsubscribe":
+			return true
+		}
+		return nil
+	})
+	defer n.Close()
+	r, err := rpc.NewRPC(n.url, &rpc.Opts{Retry: &rpc.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	defer r.Close()
+	s, err := r.Subscribe(context.Background(), "newHeads")
+	if err != nil {
+		t.Fatal(err.(*errors.Error).ErrorStack())
+	}
+	// Notifications may only arrive once the subscription is registered again, so keep pushing
+	receive := func(result string) {
+		for i := 0; i < 100; i++ {
+			n.notify("0x1", result)
+			select {
+			case got := <-s.Notifications():
+				if string(got) != `"`+result+`"` {
+					t.Errorf("Subscription received incorrect notification: %s", got)
+				}
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		t.Fatalf("Subscription did not receive %s", result)
+	}
+	receive("first")
+	n.drop()
+	receive("second")
+	if atomic.LoadInt64(&subscribes) != 2 {
+		t.Errorf("Subscription was not renewed once after reconnecting: %d", subscribes)
+	}
+	s.Unsubscribe()
+	for range s.Notifications() {
+	}
+	if s.Err() != nil {
+		t.Errorf("Unsubscribe ended with an error: %v", s.Err())
+	}
+}